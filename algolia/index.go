@@ -55,6 +55,17 @@ func getAlternativeNames(name string) []string {
 	return names
 }
 
+// Gets the alternative names of a package, including
+// the names it had before it was renamed.
+func getPackageAlternativeNames(p *packages.Package) []string {
+	names := getAlternativeNames(*p.Name)
+	for _, former := range p.FormerNames {
+		names = append(names, former)
+		names = append(names, getAlternativeNames(former)...)
+	}
+	return names
+}
+
 var githubURL = regexp.MustCompile(`github\.com[/|:]([\w\.-]+)\/([\w\.-]+)\/?`)
 
 func getGitHubMeta(repo *packages.Repository) (*GitHubMeta, error) {
@@ -139,7 +150,7 @@ func IndexPackage(p *packages.Package, index *search.Index, srimap map[string]st
 		Filename:         filename,
 		Description:      *p.Description,
 		Keywords:         p.Keywords,
-		AlternativeNames: getAlternativeNames(*p.Name),
+		AlternativeNames: getPackageAlternativeNames(p),
 		FileType:         strings.ReplaceAll(filepath.Ext(filename), ".", ""),
 		Github:           github,
 		ObjectID:         *p.Name,
@@ -155,3 +166,10 @@ func IndexPackage(p *packages.Package, index *search.Index, srimap map[string]st
 	_, err = index.SaveObject(searchEntry)
 	return &searchEntry, err
 }
+
+// DeletePackage removes a package from the Algolia index (ex. once
+// it is removed or renamed).
+func DeletePackage(name string, index *search.Index) error {
+	_, err := index.DeleteObject(name)
+	return err
+}
@@ -134,16 +134,48 @@ func NewVersionDetected(ctx context.Context, pkgName string, version string) err
 	return nil
 }
 
-const MAX_LOGS_LENGTH = 1 * 1024 * 1024 // 1 Mb
+// SkippedVersion notes that a version was not imported. Since the same
+// version is skipped on every update check, the note is only created once.
+func SkippedVersion(ctx context.Context, pkgName string, version string, reason string) error {
+	if _, err := get(ctx, pkgName, version, "skipped"); err == nil {
+		return nil
+	}
 
-func ProcessedVersion(ctx context.Context, pkgName string, version string, logs string) error {
-	// cut logs to avoid hitting the GitHub API limits
-	if len(logs) > MAX_LOGS_LENGTH {
-		logs = logs[:MAX_LOGS_LENGTH]
+	content := bytes.NewBufferString("")
+	fmt.Fprintf(content, "Skipped version: %s\n", version)
+	fmt.Fprintf(content, "reason: %s\n", reason)
+
+	if err := create(ctx, pkgName, version, "skipped", content); err != nil {
+		return errors.Wrap(err, "could not create audit log file")
 	}
+	return nil
+}
 
+// DownloadFailed notes that the tarball of a new version could not be
+// downloaded, and that the version will be retried on the next update check.
+// Since a failing download fails on every update check, the note is only
+// written when the reason changes.
+func DownloadFailed(ctx context.Context, pkgName string, version string, reason error) error {
 	content := bytes.NewBufferString("")
-	fmt.Fprintf(content, "%s", logs)
+	fmt.Fprintf(content, "Download failed: %s\n", version)
+	fmt.Fprintf(content, "reason: %s\n", reason)
+
+	if curr, err := get(ctx, pkgName, version, "download-failed"); err == nil {
+		if currContent, err := curr.GetContent(); err == nil && currContent == content.String() {
+			return nil
+		}
+	}
+
+	if err := create(ctx, pkgName, version, "download-failed", content); err != nil {
+		return errors.Wrap(err, "could not create audit log file")
+	}
+	return nil
+}
+
+const MAX_LOGS_LENGTH = 1 * 1024 * 1024 // 1 Mb
+
+func ProcessedVersion(ctx context.Context, pkgName string, version string, logs string) error {
+	content := ProcessingLog(logs)
 
 	if err := create(ctx, pkgName, version, "processing", content); err != nil {
 		return errors.Wrap(err, "could not create audit log file")
@@ -151,6 +183,28 @@ func ProcessedVersion(ctx context.Context, pkgName string, version string, logs
 	return nil
 }
 
+// ProcessingLog formats the processing audit log, with the
+// structured warnings found in the logs first.
+func ProcessingLog(logs string) *bytes.Buffer {
+	content := bytes.NewBufferString("")
+
+	if warnings := ParseWarnings(logs); len(warnings) > 0 {
+		fmt.Fprint(content, "warnings:\n")
+		for _, w := range warnings {
+			fmt.Fprintf(content, "- %s\n", w)
+		}
+		fmt.Fprint(content, "\n")
+	}
+
+	fmt.Fprintf(content, "%s", logs)
+
+	// cut the whole log, warnings included, to avoid hitting the GitHub API limits
+	if content.Len() > MAX_LOGS_LENGTH {
+		content.Truncate(MAX_LOGS_LENGTH)
+	}
+	return content
+}
+
 func WroteKV(ctx context.Context, pkgName string, version string,
 	sris map[string]string, keys []string, config string) error {
 
@@ -171,6 +225,28 @@ func WroteKV(ctx context.Context, pkgName string, version string,
 	return nil
 }
 
+// TookDownVersion notes that a version was removed from KV, with
+// the reason of the takedown and the deleted keys.
+func TookDownVersion(ctx context.Context, pkgName string, version string,
+	reason string, keys []string, latestVersion *string) error {
+	content := bytes.NewBufferString("")
+	fmt.Fprintf(content, "reason: %s\n", reason)
+	if latestVersion != nil {
+		fmt.Fprintf(content, "set last version: %s\n", *latestVersion)
+	} else {
+		fmt.Fprintf(content, "set last version: <nil>\n")
+	}
+	fmt.Fprint(content, "deleted KV keys:\n")
+	for _, key := range keys {
+		fmt.Fprintf(content, "- %s\n", key)
+	}
+
+	if err := create(ctx, pkgName, version, "takedown", content); err != nil {
+		return errors.Wrap(err, "could not create audit log file")
+	}
+	return nil
+}
+
 func WroteAlgolia(ctx context.Context, pkgName string, currVersion string, lastVersion *string, entry *algolia.SearchEntry) error {
 	content := bytes.NewBufferString("")
 	fmt.Fprintf(content, "current version: %s\n", currVersion)
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// WarningPrefix marks the structured warnings in the processing logs.
+const WarningPrefix = "warning: "
+
+// Warning is a structured warning emitted while processing a version,
+// it is reported at the top of the processing audit log.
+type Warning struct {
+	Kind    string `json:"kind"` // ex. minify-js
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// String represents the warning as a human-readable text.
+func (w Warning) String() string {
+	return fmt.Sprintf("%s %s: %s", w.Kind, w.File, w.Message)
+}
+
+// LogWarning logs a structured warning, on a single line.
+func LogWarning(w Warning) {
+	bytes, err := json.Marshal(w)
+	if err != nil {
+		log.Printf("could not encode warning %s: %s\n", w, err)
+		return
+	}
+	log.Printf("%s%s\n", WarningPrefix, bytes)
+}
+
+// ParseWarnings extracts the structured warnings from logs.
+func ParseWarnings(logs string) []Warning {
+	warnings := make([]Warning, 0)
+	for _, line := range strings.Split(logs, "\n") {
+		i := strings.Index(line, WarningPrefix+"{")
+		if i < 0 {
+			continue
+		}
+		var w Warning
+		if err := json.Unmarshal([]byte(line[i+len(WarningPrefix):]), &w); err != nil {
+			continue
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/cdnjs/tools/compress"
 	"github.com/cdnjs/tools/git"
 	"github.com/cdnjs/tools/npm"
 	"github.com/cdnjs/tools/packages"
@@ -58,6 +60,16 @@ func main() {
 				log.Fatalf("failed to show files: %s\n", err)
 			}
 
+			if errCount > 0 {
+				os.Exit(1)
+			}
+		}
+	case "match":
+		{
+			if err := matchFiles(flag.Arg(1), flag.Arg(2), noPathValidation); err != nil {
+				log.Fatalf("failed to match files: %s\n", err)
+			}
+
 			if errCount > 0 {
 				os.Exit(1)
 			}
@@ -74,14 +86,18 @@ func processVersion(ctx context.Context, pckg *packages.Package, v version.Versi
 	}
 	defer os.RemoveAll(inDir)
 
-	buff := version.DownloadTar(ctx, v)
+	tarball, err := version.DownloadTar(ctx, v)
+	if err != nil {
+		return outDir, errors.Wrap(err, "failed to download tarball")
+	}
+	defer tarball.Close()
 
 	dst, err := os.Create(path.Join(inDir, "new-version.tgz"))
 	if err != nil {
 		return outDir, errors.Wrap(err, "could not write tmp file")
 	}
 	defer dst.Close()
-	if _, err := dst.Write(buff.Bytes()); err != nil {
+	if _, err := io.Copy(dst, tarball); err != nil {
 		return outDir, errors.Wrap(err, "could not write new version in sandbox")
 	}
 
@@ -119,13 +135,44 @@ func showFiles(pckgPath string, noPathValidation bool) error {
 	// autoupdate exists, download latest versions based on source
 	src := *pckg.Autoupdate.Source
 
+	versions, err := getVersions(ctx, pckg)
+	if err != nil {
+		return err
+	}
+
+	// download into temp dir
+	if len(versions) > 0 {
+		// print info for first src version
+		if err := printMostRecentVersion(ctx, pckg, versions[0]); err != nil {
+			return errors.Wrap(err, "could not print most recent version")
+		}
+
+		// print aggregate info for the few last src versions
+		if err := printLastVersions(ctx, pckg, versions[1:]); err != nil {
+			return errors.Wrap(err, "could not print most last versions")
+		}
+	} else {
+		showErr(ctx, "no version found on "+src)
+	}
+	return nil
+}
+
+// Gets the versions of a package from its autoupdate source,
+// sorted from the most recent to the oldest.
+func getVersions(ctx context.Context, pckg *packages.Package) ([]version.Version, error) {
+	src := *pckg.Autoupdate.Source
+
 	var versions []version.Version
 
 	switch src {
 	case "npm":
 		{
+			var err error
 			// get npm versions and sort
-			versions, _ = npm.GetVersions(ctx, pckg.Autoupdate)
+			versions, _, _, err = npm.GetVersions(ctx, pckg.Autoupdate)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to retrieve npm versions")
+			}
 			sort.Sort(version.ByDate(versions))
 		}
 	case "git":
@@ -134,7 +181,17 @@ func showFiles(pckgPath string, noPathValidation bool) error {
 			// get git versions and sort
 			versions, err = git.GetVersions(ctx, pckg.Autoupdate)
 			if err != nil {
-				return errors.Wrap(err, "failed to retrieve git versions")
+				return nil, errors.Wrap(err, "failed to retrieve git versions")
+			}
+			sort.Sort(version.ByDate(versions))
+		}
+	case "github-release":
+		{
+			var err error
+			// get GitHub release versions and sort
+			versions, err = git.GetReleaseVersions(ctx, pckg.Autoupdate)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to retrieve GitHub release versions")
 			}
 			sort.Sort(version.ByDate(versions))
 		}
@@ -143,22 +200,7 @@ func showFiles(pckgPath string, noPathValidation bool) error {
 			panic(fmt.Sprintf("unknown autoupdate source: %s", src))
 		}
 	}
-
-	// download into temp dir
-	if len(versions) > 0 {
-		// print info for first src version
-		if err := printMostRecentVersion(ctx, pckg, versions[0]); err != nil {
-			return errors.Wrap(err, "could not print most recent version")
-		}
-
-		// print aggregate info for the few last src versions
-		if err := printLastVersions(ctx, pckg, versions[1:]); err != nil {
-			return errors.Wrap(err, "could not print most last versions")
-		}
-	} else {
-		showErr(ctx, "no version found on "+src)
-	}
-	return nil
+	return versions, nil
 }
 
 // Try to parse a *Package, outputting ci errors/warnings.
@@ -207,15 +249,27 @@ func parseHumanPackage(ctx context.Context, pckgPath string, noPathValidation bo
 }
 
 func filewalker(basedir string, files *[]string) filepath.WalkFunc {
+	seen := make(map[string]bool)
+	var raw map[string]bool
 	return func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return errors.Wrap(err, "failed to walk fs")
 		}
-		ext := filepath.Ext(path)
-		if ext == ".gz" {
-			path = strings.ReplaceAll(path, ".gz", "")
-			path = strings.ReplaceAll(path, basedir+"/", "")
-			*files = append(*files, path)
+		if raw == nil {
+			if raw, err = compress.ReadRawManifest(basedir); err != nil {
+				return errors.Wrap(err, "failed to read raw files")
+			}
+		}
+		name := strings.TrimPrefix(path, basedir+"/")
+		if info.IsDir() || filepath.Ext(path) == ".sri" || name == compress.RawManifestName {
+			return nil
+		}
+		// list each file once, whatever its compressed variants
+		// are, or as is if it is raw
+		name, _ = compress.OriginalName(name, raw)
+		if !seen[name] {
+			seen[name] = true
+			*files = append(*files, name)
 		}
 		return nil
 	}
@@ -335,13 +389,23 @@ func lintPackage(pckgPath string, noPathValidation bool) error {
 	case "npm":
 		{
 			// check that it exists
-			if !npm.Exists(*pckg.Autoupdate.Target) {
+			exists, err := npm.Exists(*pckg.Autoupdate.Target)
+			if err != nil {
+				showErr(ctx, fmt.Sprintf("could not check package on npm: %s", err))
+				break
+			}
+			if !exists {
 				showErr(ctx, "package doesn't exist on npm")
 				break
 			}
 
 			// check if it has enough downloads
-			if md := npm.GetMonthlyDownload(*pckg.Autoupdate.Target); md.Downloads < util.MinNpmMonthlyDownloads {
+			md, err := npm.GetMonthlyDownload(*pckg.Autoupdate.Target)
+			if err != nil {
+				showErr(ctx, fmt.Sprintf("could not get monthly downloads on npm: %s", err))
+				break
+			}
+			if md.Downloads < util.MinNpmMonthlyDownloads {
 				if !checkGitHubPopularity(ctx, pckg) {
 					showWarn(ctx, fmt.Sprintf("package download per month on npm is under %d", util.MinNpmMonthlyDownloads))
 				}
@@ -351,9 +415,19 @@ func lintPackage(pckgPath string, noPathValidation bool) error {
 		{
 			checkGitHubPopularity(ctx, pckg)
 		}
+	case "github-release":
+		{
+			// release assets are at the root of the processed version
+			for _, fileMap := range pckg.Autoupdate.FileMap {
+				if fileMap.BasePath != nil && *fileMap.BasePath != "" {
+					showErr(ctx, fmt.Sprintf("basePath `%s` is not supported for GitHub releases", *fileMap.BasePath))
+				}
+			}
+			checkGitHubPopularity(ctx, pckg)
+		}
 	default:
 		{
-			// schema will enforce npm or git, so panic
+			// schema will enforce npm, git or github-release, so panic
 			panic(fmt.Sprintf("unsupported .autoupdate.source: " + *pckg.Autoupdate.Source))
 		}
 	}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cdnjs/tools/packages"
+	"github.com/cdnjs/tools/untar"
+	"github.com/cdnjs/tools/util"
+	"github.com/cdnjs/tools/version"
+
+	"github.com/pkg/errors"
+)
+
+// Matches the fileMap of a package against a version, without running
+// the sandbox. Uses the most recent version if none is provided.
+func matchFiles(pckgPath string, versionName string, noPathValidation bool) error {
+	// create context with file path prefix, checker logger
+	ctx := util.ContextWithEntries(util.GetCheckerEntries(pckgPath, logger)...)
+
+	// parse *Package from JSON
+	pckg, err := parseHumanPackage(ctx, pckgPath, noPathValidation)
+	if err != nil {
+		return errors.Wrap(err, "could not parse package")
+	}
+	if pckg == nil {
+		return nil
+	}
+
+	versions, err := getVersions(ctx, pckg)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		showErr(ctx, "no version found on "+*pckg.Autoupdate.Source)
+		return nil
+	}
+
+	v := versions[0]
+	if versionName != "" {
+		found := false
+		for _, candidate := range versions {
+			if candidate.Version == versionName {
+				v, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			showErr(ctx, fmt.Sprintf("version `%s` not found on %s", versionName, *pckg.Autoupdate.Source))
+			return nil
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "checker-match")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	if err := extractVersion(ctx, v, dir); err != nil {
+		return errors.Wrap(err, "could not extract version")
+	}
+
+	matches, err := pckg.MatchFiles(dir)
+	if err != nil {
+		showErr(ctx, fmt.Sprintf("invalid fileMap: %s", err))
+		return nil
+	}
+
+	report, err := pckg.CheckLimits(dir, matches.Files)
+	if err != nil {
+		return errors.Wrap(err, "could not check limits")
+	}
+
+	printMatches(ctx, pckg, v, matches)
+	printLimits(ctx, report)
+	return nil
+}
+
+// Prints how the matched files compare to the package limits.
+func printLimits(ctx context.Context, report *packages.LimitsReport) {
+	fmt.Printf("\n%s", report)
+	if report.Exceeded() {
+		showErr(ctx, "version exceeds the package limits, nothing will be published")
+	}
+}
+
+// Prints the matched files, the patterns that did not match
+// and the ignored files.
+func printMatches(ctx context.Context, p *packages.Package, v version.Version, matches *packages.FileMatches) {
+	fmt.Printf("\nversion: %s\n", v.Version)
+
+	for _, pattern := range matches.UnmatchedPatterns {
+		showWarn(ctx, fmt.Sprintf("pattern `%s` did not match any file", pattern))
+	}
+	for _, f := range matches.Ignored {
+		showWarn(ctx, fmt.Sprintf("file %s ignored due to %s", f.File, f.Reason))
+	}
+
+	if len(matches.Files) == 0 {
+		showErr(ctx, fmt.Sprintf("No files will be published for version %s.\n", v.Version))
+		return
+	}
+
+	var filenameFound bool
+
+	fmt.Printf("\n```\n")
+	for _, file := range matches.Files {
+		fmt.Printf("%s\n", file.To)
+		if p.Filename != nil && file.To == *p.Filename {
+			filenameFound = true
+		}
+	}
+	fmt.Printf("```\n")
+
+	if p.Filename != nil && !filenameFound {
+		showErr(ctx, fmt.Sprintf("Filename `%s` not found in version `%s`.\n", *p.Filename, v.Version))
+	}
+}
+
+// Downloads and extracts a version into a directory, removing the
+// top-level directory of npm and git tarballs like process-version does.
+func extractVersion(ctx context.Context, v version.Version, dir string) error {
+	tarball, err := version.DownloadTar(ctx, v)
+	if err != nil {
+		return errors.Wrap(err, "failed to download tarball")
+	}
+	defer tarball.Close()
+
+	opts := untar.Options{
+		OnSkip: func(name, reason string) {
+			showWarn(ctx, fmt.Sprintf("Unsafe file ignored (%s) with name: `%s`", reason, name))
+		},
+	}
+	switch v.Source {
+	case "npm":
+		opts.Rename = func(name string) string {
+			return strings.TrimPrefix(name, "package/")
+		}
+	case "git":
+		opts.Rename = func(name string) string {
+			if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+				return parts[1]
+			}
+			return name
+		}
+	}
+
+	if err := untar.ToDir(tarball, dir, opts); err != nil {
+		return err
+	}
+
+	// the integrity of the tarball is only verified once it has been read
+	// until EOF, the tar end-of-archive marker can come before that
+	if _, err := io.Copy(ioutil.Discard, tarball); err != nil {
+		return errors.Wrap(err, "failed to read tarball")
+	}
+	return nil
+}
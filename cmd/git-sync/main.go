@@ -1,9 +1,7 @@
 package main
 
 import (
-	"archive/tar"
 	"bytes"
-	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,6 +16,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cdnjs/tools/compress"
+	"github.com/cdnjs/tools/untar"
+
 	"github.com/pkg/errors"
 )
 
@@ -37,8 +38,9 @@ func (i Item) Time() time.Time {
 }
 
 type ItemMetadata struct {
-	Pkg     string `json:"package"`
-	Version string `json:"version"`
+	Pkg      string `json:"package"`
+	Version  string `json:"version"`
+	Takedown string `json:"takedown"` // reason of the takedown of the version, if any
 }
 
 type List struct {
@@ -107,6 +109,16 @@ func main() {
 	lastSuccessfullSync := lastSync
 
 	for _, version := range newVersions {
+		if version.Metadata.Takedown != "" {
+			t, err := removeVersion(version)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to remove version: %s\n", err)
+			} else {
+				lastSuccessfullSync = *t
+			}
+			continue
+		}
+
 		t, err := addNewVersion(version)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to add new version: %s\n", err)
@@ -149,11 +161,15 @@ func dirExists(path string) bool {
 func addNewVersion(item Item) (*time.Time, error) {
 	log.Printf("add new version %s %s", item.Metadata.Pkg, item.Metadata.Version)
 
-	tar, err := download(item)
+	body, err := download(item)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not download object")
 	}
-	defer tar.Close()
+	defer body.Close()
+	archive, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read object")
+	}
 
 	dest := fmt.Sprintf("ajax/libs/%s/%s", item.Metadata.Pkg, item.Metadata.Version)
 	if dirExists(dest) {
@@ -168,11 +184,33 @@ func addNewVersion(item Item) (*time.Time, error) {
 		return nil, errors.Wrap(err, "failed to create version directory")
 	}
 
+	// the raw manifest may follow the files, read it first
+	raw := make(map[string]bool)
+	onManifest := func(name string, r io.Reader) error {
+		if name != compress.RawManifestName {
+			return nil
+		}
+		files, err := compress.ParseRawManifest(r)
+		if err != nil {
+			return err
+		}
+		raw = files
+		return nil
+	}
+	if err := untar.Inflate(bytes.NewReader(archive), onManifest); err != nil {
+		return nil, errors.Wrap(err, "failed to extract files")
+	}
+
 	hasFiles := false
+	restored := make(map[string]bool)
 	onFile := func(name string, r io.Reader) error {
 		ext := filepath.Ext(name)
-		if ext == ".woff2" {
-			// woff2 files are not compressed, write as is
+		if ext == ".sri" || name == compress.RawManifestName {
+			return nil
+		}
+		original, compressed := compress.OriginalName(name, raw)
+		if !compressed {
+			// raw files (ex. woff2) are not compressed, write as is
 			target := path.Join(dest, name)
 			if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
 				return errors.Wrap(err, "failed to create directory")
@@ -180,26 +218,30 @@ func addNewVersion(item Item) (*time.Time, error) {
 			if err := writeFile(target, r); err != nil {
 				return errors.Wrap(err, "failed to write file")
 			}
-		}
-		if ext == ".gz" {
-			name = strings.ReplaceAll(name, ".gz", "")
-			target := path.Join(dest, name)
+		} else {
+			// compressed variants (.br, .gz, .zst) have the same content,
+			// restore the original file from the first one
+			if restored[original] {
+				return nil
+			}
+			target := path.Join(dest, original)
 			if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
 				return errors.Wrap(err, "failed to create directory")
 			}
-			uncompressed, err := gunzip(r)
-			if err != nil {
+			var uncompressed bytes.Buffer
+			if err := compress.Decode(ext, &uncompressed, r); err != nil {
 				return errors.Wrap(err, "failed to uncompress")
 			}
-			if err := writeFile(target, bytes.NewReader(uncompressed)); err != nil {
+			if err := writeFile(target, &uncompressed); err != nil {
 				return errors.Wrap(err, "failed to write file")
 			}
+			restored[original] = true
 		}
 
 		hasFiles = true
 		return nil
 	}
-	if err := inflate(tar, onFile); err != nil {
+	if err := untar.Inflate(bytes.NewReader(archive), onFile); err != nil {
 		return nil, errors.Wrap(err, "failed to extract files")
 	}
 
@@ -220,6 +262,31 @@ func addNewVersion(item Item) (*time.Time, error) {
 	return &t, nil
 }
 
+// Removes a version that was taken down.
+func removeVersion(item Item) (*time.Time, error) {
+	log.Printf("takedown version %s %s: %s", item.Metadata.Pkg, item.Metadata.Version, item.Metadata.Takedown)
+
+	dest := fmt.Sprintf("ajax/libs/%s/%s", item.Metadata.Pkg, item.Metadata.Version)
+	if !dirExists(dest) {
+		log.Printf("version %s does not exist, ignoring\n", dest)
+
+		t := item.Time()
+		return &t, nil
+	}
+
+	if err := git("rm", "-r", "--quiet", dest); err != nil {
+		return nil, errors.Wrap(err, "failed to run git")
+	}
+
+	commitMsg := fmt.Sprintf("Remove %s (%s)\n\n%s", item.Metadata.Pkg, item.Metadata.Version, item.Metadata.Takedown)
+	if err := git("commit", "-m", commitMsg); err != nil {
+		return nil, errors.Wrap(err, "failed to run git")
+	}
+
+	t := item.Time()
+	return &t, nil
+}
+
 func git(args ...string) error {
 	cmd := exec.Command("git", args...)
 	log.Printf("running: %s", cmd)
@@ -292,55 +359,3 @@ func diff(lastSync time.Time, items []Item) ([]Item, error) {
 	}
 	return changes, nil
 }
-
-func inflate(gzipStream io.Reader, onFile func(string, io.Reader) error) error {
-	uncompressedStream, err := gzip.NewReader(gzipStream)
-	if err != nil {
-		return errors.Wrap(err, "ExtractTarGz: NewReader failed")
-	}
-
-	tarReader := tar.NewReader(uncompressedStream)
-
-	for {
-		header, err := tarReader.Next()
-
-		if err == io.EOF {
-			break
-		}
-
-		if err != nil {
-			return errors.Wrap(err, "ExtractTarGz: Next() faileds")
-		}
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			// ignore
-		case tar.TypeReg:
-			if err := onFile(header.Name, tarReader); err != nil {
-				return errors.Wrap(err, "failed to handle file")
-			}
-		default:
-			return errors.Errorf(
-				"ExtractTarGz: uknown type: %x in %s",
-				header.Typeflag,
-				header.Name)
-		}
-
-	}
-	return nil
-}
-
-func gunzip(in io.Reader) ([]byte, error) {
-	r, err := gzip.NewReader(in)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create gzip reader")
-	}
-
-	var out bytes.Buffer
-	_, err = out.ReadFrom(r)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to read from gzip")
-	}
-
-	return out.Bytes(), nil
-}
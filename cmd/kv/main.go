@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cdnjs/tools/algolia"
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ENV           = os.Getenv("ENV")
+	KV_TOKEN      = os.Getenv("KV_TOKEN")
+	CF_ACCOUNT_ID = os.Getenv("CF_ACCOUNT_ID")
+)
+
+func main() {
+	var fsRoot string
+	var repair bool
+	flag.StringVar(&fsRoot, "fs", "", "If set, the KV stored on disk under this directory is used instead of Workers KV.")
+	flag.BoolVar(&repair, "repair", false, "If set, audit repairs the aggregated metadata and package entries.")
+	flag.Parse()
+
+	store, err := newStore(fsRoot)
+	if err != nil {
+		log.Fatalf("failed to create store: %s\n", err)
+	}
+	ctx := context.Background()
+
+	switch subcommand := flag.Arg(0); subcommand {
+	case "verify-sri":
+		{
+			ok := true
+			for _, pkg := range flag.Args()[1:] {
+				report, err := kv.VerifySRIs(ctx, store, pkg)
+				if err != nil {
+					log.Fatalf("failed to verify SRIs of %s: %s\n", pkg, err)
+				}
+				printSRIReport(report)
+				ok = ok && report.OK()
+			}
+
+			if !ok {
+				os.Exit(1)
+			}
+		}
+	case "audit":
+		{
+			pkgs := flag.Args()[1:]
+			if len(pkgs) == 0 {
+				if pkgs, err = kv.ListPackages(ctx, store); err != nil {
+					log.Fatalf("failed to list packages: %s\n", err)
+				}
+			}
+
+			unrepaired := 0
+			for _, pkg := range pkgs {
+				report, err := kv.AuditPackage(ctx, store, pkg, repair)
+				if err != nil {
+					log.Fatalf("failed to audit %s: %s\n", pkg, err)
+				}
+				for _, i := range report {
+					fmt.Printf("%s: %s\n", pkg, i)
+					if !i.Repaired {
+						unrepaired++
+					}
+				}
+			}
+			fmt.Printf("audited %d packages\n", len(pkgs))
+
+			if unrepaired > 0 {
+				os.Exit(1)
+			}
+		}
+	case "rename":
+		{
+			oldName, newName := flag.Arg(1), flag.Arg(2)
+			if flag.NArg() != 3 || oldName == "" || newName == "" {
+				log.Fatal("usage: kv rename <old-name> <new-name>")
+			}
+			if err := renamePackage(ctx, store, oldName, newName); err != nil {
+				log.Fatalf("failed to rename %s to %s: %s\n", oldName, newName, err)
+			}
+		}
+	case "remove":
+		{
+			for _, pkg := range flag.Args()[1:] {
+				if err := removePackage(ctx, store, pkg); err != nil {
+					log.Fatalf("failed to remove %s: %s\n", pkg, err)
+				}
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unknown subcommand: `%s`", subcommand))
+	}
+}
+
+func newStore(fsRoot string) (kv.Store, error) {
+	if fsRoot != "" {
+		return kv.NewFSStore(fsRoot)
+	}
+	return kv.NewCloudflareStoreFromEnv(KV_TOKEN, CF_ACCOUNT_ID)
+}
+
+func printSRIReport(r *kv.SRIReport) {
+	fmt.Printf("%s: %d SRIs verified\n", r.Package, r.Checked)
+	for _, m := range r.Mismatches {
+		fmt.Printf("mismatch %s\n", m)
+	}
+	for _, key := range r.MissingSRIs {
+		fmt.Printf("missing SRI %s\n", key)
+	}
+	for _, key := range r.MissingFiles {
+		fmt.Printf("missing file %s\n", key)
+	}
+	for _, key := range r.OrphanedSRIs {
+		fmt.Printf("orphaned SRI %s\n", key)
+	}
+	if len(r.Unsigned) > 0 {
+		fmt.Printf("%d files of versions published before every file had an SRI have none\n", len(r.Unsigned))
+	}
+}
+
+func renamePackage(ctx context.Context, store kv.Store, oldName, newName string) error {
+	if err := kv.RenamePackage(ctx, store, oldName, newName); err != nil {
+		return err
+	}
+	log.Printf("renamed %s to %s\n", oldName, newName)
+
+	if ENV != "prod" {
+		log.Printf("algolia doesn't update in %s\n", ENV)
+		return nil
+	}
+
+	pkg, err := kv.GetPackage(ctx, store, newName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get renamed package")
+	}
+	sris, err := kv.GetSRIManifest(ctx, store, newName, *pkg.Version)
+	if err != nil {
+		log.Printf("could not get SRIs of %s: %s\n", *pkg.Version, err)
+		sris = make(map[string]string)
+	}
+
+	index := algolia.GetProdIndex(algolia.GetClient())
+	if _, err := algolia.IndexPackage(pkg, index, sris); err != nil {
+		return errors.Wrap(err, "failed to index renamed package")
+	}
+	if err := algolia.DeletePackage(oldName, index); err != nil {
+		return errors.Wrap(err, "failed to remove package from index")
+	}
+	return nil
+}
+
+func removePackage(ctx context.Context, store kv.Store, pkg string) error {
+	deleted, err := kv.RemovePackage(ctx, store, pkg)
+	if err != nil {
+		return err
+	}
+	for _, ns := range kv.Namespaces {
+		fmt.Printf("%s: deleted %d keys in %s\n", pkg, len(deleted[ns]), ns)
+	}
+
+	if ENV != "prod" {
+		log.Printf("algolia doesn't update in %s\n", ENV)
+		return nil
+	}
+
+	index := algolia.GetProdIndex(algolia.GetClient())
+	if err := algolia.DeletePackage(pkg, index); err != nil {
+		return errors.Wrap(err, "failed to remove package from index")
+	}
+	return nil
+}
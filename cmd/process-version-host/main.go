@@ -1,9 +1,7 @@
 package main
 
 import (
-	"archive/tar"
 	"bytes"
-	"compress/gzip"
 	"context"
 	b64 "encoding/base64"
 	"encoding/json"
@@ -14,13 +12,12 @@ import (
 	"net/http"
 	"os"
 	"path"
-	"path/filepath"
 	"runtime"
-	"strings"
 
 	"github.com/cdnjs/tools/audit"
 	"github.com/cdnjs/tools/sandbox"
 	"github.com/cdnjs/tools/sentry"
+	"github.com/cdnjs/tools/untar"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/pkg/errors"
@@ -103,9 +100,9 @@ func processMessage(ctx context.Context, data []byte) error {
 	}
 
 	name := fmt.Sprintf("%s_%s", message.Pkg, message.Version)
-	logs, err := sandbox.Run(ctx, name, inDir, outDir)
-	if err != nil {
-		return errors.Wrap(err, "failed to run sandbox")
+	logs, runErr := sandbox.Run(ctx, name, inDir, outDir)
+	if _, ok := runErr.(sandbox.ExitError); runErr != nil && !ok {
+		return errors.Wrap(runErr, "failed to run sandbox")
 	}
 	log.Println("logs", len(logs), logs)
 
@@ -113,9 +110,15 @@ func processMessage(ctx context.Context, data []byte) error {
 		return errors.Wrap(err, "could not post audit")
 	}
 
+	// the logs explain why processing failed (ex. the package limits
+	// were exceeded), do not publish a partial output
+	if runErr != nil {
+		return errors.Wrap(runErr, "failed to process version")
+	}
+
 	log.Printf("compressing %s\n", outDir)
 	var buff bytes.Buffer
-	if err := compress(outDir, &buff); err != nil {
+	if err := untar.Pack(outDir, &buff); err != nil {
 		return errors.Wrap(err, "failed to compress out dir")
 	}
 
@@ -179,58 +182,3 @@ func download(dstDir string, url string) error {
 	_, err = io.Copy(dst, resp.Body)
 	return err
 }
-
-func compress(src string, buf io.Writer) error {
-	// tar > gzip > buf
-	zr := gzip.NewWriter(buf)
-	tw := tar.NewWriter(zr)
-
-	// walk through every file in the folder
-	err := filepath.Walk(src, func(file string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		// generate tar header
-		header, err := tar.FileInfoHeader(fi, file)
-		if err != nil {
-			return err
-		}
-
-		// remove the /tmp/out** prefix
-		relFile := strings.ReplaceAll(file, src, "")
-
-		// must provide real name
-		// (see https://golang.org/src/archive/tar/common.go?#L626)
-		header.Name = filepath.ToSlash(relFile)
-
-		// write header
-		if err := tw.WriteHeader(header); err != nil {
-			return err
-		}
-		// if not a dir, write file content
-		if !fi.IsDir() {
-			data, err := os.Open(file)
-			if err != nil {
-				return err
-			}
-			if _, err := io.Copy(tw, data); err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return err
-	}
-
-	// produce tar
-	if err := tw.Close(); err != nil {
-		return err
-	}
-	// produce gzip
-	if err := zr.Close(); err != nil {
-		return err
-	}
-	//
-	return nil
-}
@@ -1,8 +1,6 @@
 package main
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -16,24 +14,31 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/cdnjs/tools/audit"
 	"github.com/cdnjs/tools/compress"
 	"github.com/cdnjs/tools/packages"
 	"github.com/cdnjs/tools/sri"
+	"github.com/cdnjs/tools/untar"
 
 	"github.com/pkg/errors"
 )
 
 var (
 	// these file extensions will be uploaded to KV
-	// but not compressed
+	// but not compressed, other files are only uploaded
+	// uncompressed if compressing them does not reduce their size
 	doNotCompress = map[string]bool{
 		".woff2": true,
 	}
-	// we calculate SRIs for these file extensions
-	calculateSRI = map[string]bool{
-		".js":  true,
-		".css": true,
-	}
+	// the compressed encodings of the files uploaded to KV
+	encoders = compress.Encoders()
+)
+
+// the files emitted raw, listed in the raw manifest once
+// all the files are emitted
+var (
+	rawFiles   = make([]string, 0)
+	rawFilesMu sync.Mutex
 )
 
 const (
@@ -81,34 +86,137 @@ func (j optimizeJob) clone() optimizeJob {
 }
 
 func (j optimizeJob) emitFromWorkspace(src string) {
+	if j.Dest == compress.RawManifestName {
+		audit.LogWarning(audit.Warning{Kind: "reserved-name", File: j.File, Message: "the name is reserved, file ignored"})
+		return
+	}
 	dest := path.Join(OUTPUT, j.Dest)
 	if err := os.MkdirAll(path.Dir(dest), 0755); err != nil {
 		log.Fatalf("could not create dest dir: %s", err)
 	}
 
+	// every published file has an SRI
+	outSRI := fmt.Sprintf("%s.sri", dest)
+	sri.CalculateFileSRI(src, outSRI)
+	log.Printf("sri %s -> %s\n", src, outSRI)
+
 	ext := path.Ext(src)
-	if _, ok := calculateSRI[ext]; ok {
-		outSRI := fmt.Sprintf("%s.sri", dest)
-		sri.CalculateFileSRI(src, outSRI)
-		log.Printf("sri %s -> %s\n", src, outSRI)
-	}
-
-	if _, ok := doNotCompress[ext]; !ok {
-		outBr := fmt.Sprintf("%s.br", dest)
-		compress.Brotli11CLI(j.Ctx, src, outBr)
-		log.Printf("br %s -> %s\n", src, outBr)
-
-		outGz := fmt.Sprintf("%s.gz", dest)
-		compress.Gzip9Native(j.Ctx, src, outGz)
-		log.Printf("gz %s -> %s\n", src, outGz)
-	} else {
-		if err := copyFile(src, dest); err != nil {
-			log.Fatalf("failed to copy file: %s", err)
+	if _, ok := doNotCompress[ext]; !ok && j.emitCompressed(src, dest) {
+		return
+	}
+
+	if err := copyFile(src, dest); err != nil {
+		log.Fatalf("failed to copy file: %s", err)
+	}
+	// list the file as raw, its extension may be the one of a compressed variant
+	rawFilesMu.Lock()
+	rawFiles = append(rawFiles, j.Dest)
+	rawFilesMu.Unlock()
+	log.Printf("copy %s -> %s\n", src, dest)
+}
+
+// Emits the compressed variants of a file, returning false if the file
+// is incompressible and should be emitted as is instead.
+func (j optimizeJob) emitCompressed(src, dest string) bool {
+	alreadyCompressed, err := compress.IsCompressedFormat(src)
+	if err != nil {
+		log.Fatalf("failed to detect format of %s: %s", src, err)
+	}
+	if alreadyCompressed {
+		log.Printf("%s is already compressed\n", src)
+		return false
+	}
+
+	fileEncoders := make([]compress.Encoder, 0, len(encoders))
+	for _, e := range encoders {
+		if _, ok := e.(compress.Zstd); ok && !j.Optimization.Zstd() {
+			continue
+		}
+		fileEncoders = append(fileEncoders, e)
+	}
+
+	smaller, err := compress.EncodeFiles(fileEncoders, src, dest)
+	if err != nil {
+		log.Fatalf("failed to compress %s: %s", src, err)
+	}
+	if !smaller {
+		log.Printf("%s is not smaller once compressed\n", src)
+		return false
+	}
+
+	for _, e := range fileEncoders {
+		log.Printf("%s %s -> %s\n", strings.TrimPrefix(e.Ext(), "."), src, dest+e.Ext())
+	}
+	return true
+}
+
+// Emits the WebP and AVIF derivatives of an image, if enabled.
+func (j optimizeJob) emitDerivatives(image string) {
+	formats := make([]compress.ImageFormat, 0)
+	if j.Optimization.Webp() {
+		formats = append(formats, compress.WebP)
+	}
+	if j.Optimization.Avif() {
+		formats = append(formats, compress.AVIF)
+	}
+
+	for _, format := range formats {
+		out, err := compress.Derivative(j.Ctx, image, format)
+		if err != nil {
+			audit.LogWarning(audit.Warning{Kind: "derivative", File: j.File, Message: err.Error()})
+		}
+		if out != nil {
+			j := j.clone()
+			j.Dest += format.Ext
+			j.emitFromWorkspace(*out)
 		}
-		log.Printf("copy %s -> %s\n", src, dest)
 	}
 }
 
+// Returns the JavaScript minifiers of the package, in the order to try them.
+func (j optimizeJob) jsMinifiers() []compress.JsMinifier {
+	names := j.Optimization.JsMinifier().Names()
+	if names == nil {
+		names = compress.DefaultJsMinifiers
+	}
+
+	minifiers := make([]compress.JsMinifier, 0, len(names))
+	for _, name := range names {
+		m, err := compress.GetJsMinifier(name)
+		if err != nil {
+			log.Fatalf("invalid minifier: %s", err)
+		}
+		minifiers = append(minifiers, m)
+	}
+	return minifiers
+}
+
+// Returns the JavaScript minification options of the package.
+func (j optimizeJob) minifyOptions() compress.MinifyOptions {
+	m := j.Optimization.JsMinifier()
+	return compress.MinifyOptions{
+		Mangle:    m.ShouldMangle(),
+		Compress:  m.ShouldCompress(),
+		Target:    m.GetTarget(),
+		SourceMap: j.Optimization.PublishSourceMaps(),
+	}
+}
+
+// Emits the source map of a minified file, if any.
+func (j optimizeJob) emitSourceMap(minified string) {
+	if !j.Optimization.PublishSourceMaps() {
+		return
+	}
+	src := minified + ".map"
+	if _, err := os.Stat(src); err != nil {
+		log.Printf("no source map for %s\n", minified)
+		return
+	}
+	j = j.clone()
+	j.Dest += ".map"
+	j.emitFromWorkspace(src)
+}
+
 func (j optimizeJob) emit(name string) {
 	src := path.Join(WORKSPACE, name)
 	j.emitFromWorkspace(src)
@@ -147,54 +255,24 @@ func extractInput(source string) error {
 	if err != nil {
 		return errors.Wrap(err, "could not open input")
 	}
+	defer gzipStream.Close()
 
-	uncompressedStream, err := gzip.NewReader(gzipStream)
-	if err != nil {
-		return errors.Wrap(err, "could not create reader")
+	opts := untar.Options{
+		OnSkip: func(name, reason string) {
+			log.Printf("ExtractTarGz: ignoring %s: %s\n", name, reason)
+		},
+	}
+	if source == "npm" {
+		// remove package folder
+		opts.Rename = removePackageDir
+	}
+	if source == "git" {
+		// remove package folder
+		opts.Rename = removeFirstDir
 	}
 
-	tarReader := tar.NewReader(uncompressedStream)
-
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("ExtractTarGz: Next() failed: %s", err.Error())
-		}
-
-		target := header.Name
-		if source == "npm" {
-			// remove package folder
-			target = removePackageDir(header.Name)
-		}
-		if source == "git" {
-			// remove package folder
-			target = removeFirstDir(header.Name)
-		}
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			// ignore dirs
-		case tar.TypeReg:
-			if err := os.MkdirAll(path.Join(WORKSPACE, filepath.Dir(target)), 0755); err != nil {
-				return errors.Wrap(err, "ExtractTarGz: Mkdir() failed")
-			}
-			outFile, err := os.Create(path.Join(WORKSPACE, target))
-			if err != nil {
-				return errors.Wrap(err, "ExtractTarGz: Create() failed")
-			}
-			defer outFile.Close()
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				return errors.Wrap(err, "ExtractTarGz: Copy() failed")
-			}
-		default:
-			log.Printf(
-				"ExtractTarGz: uknown type: %x in %s\n",
-				header.Typeflag,
-				header.Name)
-		}
+	if err := untar.ToDir(gzipStream, WORKSPACE, opts); err != nil {
+		return errors.Wrap(err, "ExtractTarGz failed")
 	}
 	return nil
 }
@@ -208,24 +286,48 @@ func optimizeWorker(wg *sync.WaitGroup, jobs <-chan optimizeJob) {
 			if j.Optimization.Jpg() {
 				compress.Jpeg(j.Ctx, intputFile)
 			}
+			j.emitDerivatives(intputFile)
 		case ".png":
 			if j.Optimization.Png() {
 				compress.Png(j.Ctx, intputFile)
 			}
+			j.emitDerivatives(intputFile)
 		case ".js":
 			if j.Optimization.Js() {
-				if out := compress.Js(j.Ctx, intputFile); out != nil {
+				out, err := compress.Js(j.Ctx, intputFile, j.jsMinifiers(), j.minifyOptions())
+				if err != nil {
+					audit.LogWarning(audit.Warning{Kind: "minify-js", File: j.File, Message: err.Error()})
+				}
+				if out != nil {
 					j := j.clone()
 					j.Dest = strings.Replace(j.Dest, ".js", ".min.js", 1)
 					j.emitFromWorkspace(*out)
+					j.emitSourceMap(*out)
 				}
 			}
 		case ".css":
 			if j.Optimization.Css() {
-				if out := compress.CSS(j.Ctx, intputFile); out != nil {
+				out, err := compress.CSS(j.Ctx, intputFile, j.Optimization.PublishSourceMaps())
+				if err != nil {
+					audit.LogWarning(audit.Warning{Kind: "minify-css", File: j.File, Message: err.Error()})
+				}
+				if out != nil {
 					j := j.clone()
 					j.Dest = strings.Replace(j.Dest, ".css", ".min.css", 1)
 					j.emitFromWorkspace(*out)
+					j.emitSourceMap(*out)
+				}
+			}
+		case ".svg":
+			if j.Optimization.Svg() {
+				out, err := compress.SVG(j.Ctx, intputFile)
+				if err != nil {
+					audit.LogWarning(audit.Warning{Kind: "minify-svg", File: j.File, Message: err.Error()})
+				}
+				if out != nil {
+					j := j.clone()
+					j.Dest = strings.Replace(j.Dest, ".svg", ".min.svg", 1)
+					j.emitFromWorkspace(*out)
 				}
 			}
 		}
@@ -237,13 +339,28 @@ func optimizeWorker(wg *sync.WaitGroup, jobs <-chan optimizeJob) {
 
 // Optimizes/minifies package's files on disk for a particular package version.
 func optimizePackage(ctx context.Context, config *packages.Package) error {
-	log.Printf("optimizing files (Js %t, Css %t, Png %t, Jpg %t)\n",
+	log.Printf("optimizing files (Js %t, Css %t, Png %t, Jpg %t, Svg %t, Zstd %t, SourceMaps %t, Webp %t, Avif %t)\n",
 		config.Optimization.Js(),
 		config.Optimization.Css(),
 		config.Optimization.Png(),
-		config.Optimization.Jpg())
+		config.Optimization.Jpg(),
+		config.Optimization.Svg(),
+		config.Optimization.Zstd(),
+		config.Optimization.PublishSourceMaps(),
+		config.Optimization.Webp(),
+		config.Optimization.Avif())
 
 	files := config.NpmFilesFrom(WORKSPACE)
+
+	report, err := config.CheckLimits(WORKSPACE, files)
+	if err != nil {
+		return errors.Wrap(err, "could not check limits")
+	}
+	log.Printf("limits:\n%s", report)
+	if report.Exceeded() {
+		return errors.New("version exceeds the package limits, nothing will be published")
+	}
+
 	cpuCount := runtime.NumCPU()
 	jobs := make(chan optimizeJob, cpuCount)
 
@@ -265,6 +382,12 @@ func optimizePackage(ctx context.Context, config *packages.Package) error {
 	close(jobs)
 
 	wg.Wait()
+
+	if len(rawFiles) > 0 {
+		if err := compress.WriteRawManifest(OUTPUT, rawFiles); err != nil {
+			return errors.Wrap(err, "could not list raw files")
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cdnjs/tools/gcp"
+	"github.com/cdnjs/tools/kv"
+)
+
+var (
+	KV_TOKEN        = os.Getenv("KV_TOKEN")
+	CF_ACCOUNT_ID   = os.Getenv("CF_ACCOUNT_ID")
+	OUTGOING_BUCKET = os.Getenv("OUTGOING_BUCKET")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() < 3 {
+		log.Fatal("usage: takedown <package> <version> <reason>")
+	}
+	pkgName, version := flag.Arg(0), flag.Arg(1)
+	reason := strings.Join(flag.Args()[2:], " ")
+	ctx := context.Background()
+
+	store, err := kv.NewCloudflareStoreFromEnv(KV_TOKEN, CF_ACCOUNT_ID)
+	if err != nil {
+		log.Fatalf("failed to create cloudflare API client: %s\n", err)
+	}
+
+	pkg, err := kv.GetPackage(ctx, store, pkgName)
+	if err != nil {
+		log.Fatalf("failed to get package %s: %s\n", pkgName, err)
+	}
+
+	if _, err := kv.GetVersion(ctx, store, pkgName+"/"+version); err != nil {
+		// the version may still be in git, continue
+		log.Printf("version %s not found in KV: %s\n", version, err)
+	}
+
+	if err := gcp.AddTakedown(ctx, OUTGOING_BUCKET, pkg, version, reason); err != nil {
+		log.Fatalf("failed to takedown %s %s: %s\n", pkgName, version, err)
+	}
+	log.Printf("takedown of %s %s requested\n", pkgName, version)
+}
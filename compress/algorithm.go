@@ -3,71 +3,165 @@ package compress
 import (
 	"bytes"
 	"compress/gzip"
-	"context"
-	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
-	"os/exec"
+	"os"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/cdnjs/tools/util"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
 )
 
-// Runs an algorithm with a set of arguments,
-// and returns its stdout as bytes.
-// Note, this function will panic if anything is
-// output to stderr.
-func runAlgorithm(ctx context.Context, alg string, args ...string) []byte {
-	cmd := exec.Command(alg, args...)
-	var stdOut, stdErr bytes.Buffer
-	cmd.Stdout, cmd.Stderr = &stdOut, &stdErr
-
-	log.Printf("algorithm: run %s\n", cmd)
-	err := cmd.Run()
-	// log.Println(string(stdOut.Bytes()))
-	// log.Println(string(stdErr.Bytes()))
-	util.Check(err)
+// Default compression levels, optimal compression.
+const (
+	DefaultBrotliQuality = brotli.BestCompression
+	DefaultGzipLevel     = gzip.BestCompression
+)
+
+// Encoder compresses a stream into a file encoding.
+// The output only depends on the input and the encoder's level.
+type Encoder interface {
+	// Ext returns the extension of the compressed files (ex. `.br`).
+	Ext() string
+
+	// Encode writes the compressed content of r to w.
+	Encode(w io.Writer, r io.Reader) error
+}
 
-	if stdErr.Len() > 0 {
-		panic(fmt.Sprintf("%s failed: %s", alg, stdErr.String()))
+// Brotli compresses streams with brotli at a quality between 0 and 11.
+type Brotli struct {
+	Quality int
+}
+
+// Ext returns the extension of brotli compressed files.
+func (b Brotli) Ext() string {
+	return ".br"
+}
+
+// Encode writes the brotli compressed content of r to w.
+func (b Brotli) Encode(w io.Writer, r io.Reader) error {
+	bw := brotli.NewWriterLevel(w, b.Quality)
+	if _, err := io.Copy(bw, r); err != nil {
+		bw.Close()
+		return errors.Wrap(err, "could not compress with brotli")
+	}
+	if err := bw.Close(); err != nil {
+		return errors.Wrap(err, "could not compress with brotli")
 	}
+	return nil
+}
 
-	return stdOut.Bytes()
+// Gzip compresses streams with gzip at a level between 1 and 9.
+// The gzip header has no file name nor modification time.
+type Gzip struct {
+	Level int
 }
 
-// Brotli11CLI returns a brotli compressed file as bytes
-// at optimal compression (quality 11).
-func Brotli11CLI(ctx context.Context, src string, out string) {
-	runAlgorithm(ctx, "brotli", "--quality=11", "--output="+out, src)
+// Ext returns the extension of gzip compressed files.
+func (g Gzip) Ext() string {
+	return ".gz"
 }
 
-// UnBrotliCLI returns a brotli compressed file as bytes
-// at optimal compression (quality 11).
-func UnBrotliCLI(ctx context.Context, filePath string) []byte {
-	return runAlgorithm(ctx, "brotli", "--decompress", "--input", filePath)
+// Encode writes the gzip compressed content of r to w.
+func (g Gzip) Encode(w io.Writer, r io.Reader) error {
+	gw, err := gzip.NewWriterLevel(w, g.Level)
+	if err != nil {
+		return errors.Wrap(err, "invalid gzip level")
+	}
+	if _, err := io.Copy(gw, r); err != nil {
+		gw.Close()
+		return errors.Wrap(err, "could not compress with gzip")
+	}
+	if err := gw.Close(); err != nil {
+		return errors.Wrap(err, "could not compress with gzip")
+	}
+	return nil
 }
 
-// Gzip9Native returns a gzip compressed file as bytes
-// at optimal compression (level 9).
-func Gzip9Native(ctx context.Context, src string, out string) {
-	uncompressed, err := ioutil.ReadFile(src)
-	util.Check(err)
+// Encoders returns the encoders of the files published to KV. Their
+// levels can be configured with the BROTLI_QUALITY, GZIP_LEVEL and
+// ZSTD_LEVEL environment variables, defaulting to optimal compression.
+func Encoders() []Encoder {
+	return []Encoder{
+		Brotli{levelFromEnv("BROTLI_QUALITY", DefaultBrotliQuality, brotli.BestSpeed, brotli.BestCompression)},
+		Gzip{levelFromEnv("GZIP_LEVEL", DefaultGzipLevel, gzip.BestSpeed, gzip.BestCompression)},
+		Zstd{levelFromEnv("ZSTD_LEVEL", DefaultZstdLevel, 1, DefaultZstdLevel)},
+	}
+}
 
-	bytes := Gzip9Bytes(uncompressed)
+// TrimExt removes the extension of a compressed file (ex. `a.js.br` -> `a.js`),
+// returning false if the file is not compressed by one of the encoders.
+func TrimExt(name string) (string, bool) {
+	switch ext := path.Ext(name); ext {
+	case Brotli{}.Ext(), Gzip{}.Ext(), Zstd{}.Ext():
+		return strings.TrimSuffix(name, ext), true
+	}
+	return name, false
+}
 
-	err = ioutil.WriteFile(out, bytes, 0644)
-	util.Check(err)
+// Decode writes the uncompressed content of r to w, using the
+// decoder of the extension of a compressed file.
+func Decode(ext string, w io.Writer, r io.Reader) error {
+	switch ext {
+	case Brotli{}.Ext():
+		_, err := io.Copy(w, brotli.NewReader(r))
+		return errors.Wrap(err, "could not uncompress brotli")
+	case Gzip{}.Ext():
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return errors.Wrap(err, "could not create gzip reader")
+		}
+		_, err = io.Copy(w, gr)
+		return errors.Wrap(err, "could not uncompress gzip")
+	case Zstd{}.Ext():
+		return UnZstd(w, r)
+	}
+	return errors.Errorf("unknown compressed extension `%s`", ext)
 }
 
-func Gzip9Bytes(uncompressed []byte) []byte {
-	var b bytes.Buffer
+// Reads a compression level from the environment.
+func levelFromEnv(name string, def, min, max int) int {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	level, err := strconv.Atoi(v)
+	if err != nil || level < min || level > max {
+		log.Printf("invalid %s `%s`, using %d\n", name, v, def)
+		return def
+	}
+	return level
+}
 
-	w, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
-	util.Check(err)
+// EncodeFile compresses the file src into dest with an encoder.
+func EncodeFile(e Encoder, src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "could not open source file")
+	}
+	defer in.Close()
 
-	_, err = w.Write(uncompressed)
-	util.Check(err)
-	util.Check(w.Close())
+	out, err := os.Create(dest)
+	if err != nil {
+		return errors.Wrap(err, "could not create dest file")
+	}
+	defer out.Close()
+
+	if err := e.Encode(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
 
+// Gzip9Bytes returns gzip compressed bytes
+// at optimal compression (level 9).
+func Gzip9Bytes(uncompressed []byte) []byte {
+	var b bytes.Buffer
+	util.Check(Gzip{DefaultGzipLevel}.Encode(&b, bytes.NewReader(uncompressed)))
 	return b.Bytes()
 }
 
@@ -84,3 +178,12 @@ func UnGzip(compressed []byte) []byte {
 
 	return res.Bytes()
 }
+
+// UnBrotli uncompresses a brotli file as bytes.
+func UnBrotli(compressed []byte) []byte {
+	var res bytes.Buffer
+	_, err := res.ReadFrom(brotli.NewReader(bytes.NewReader(compressed)))
+	util.Check(err)
+
+	return res.Bytes()
+}
@@ -4,30 +4,33 @@ import (
 	"context"
 	"log"
 	"os"
-	"os/exec"
 	"path"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // Extensions the compression handle
 var (
-	cleanCSS = "/node_modules/clean-css-cli/bin/cleancss"
+	CLEANCSS = "/node_modules/clean-css-cli/bin/cleancss"
 )
 
-// CSS performs a compression of the file.
-func CSS(ctx context.Context, file string) *string {
+// CSS performs a compression of the file. If sourceMap is true, the source
+// map is written next to the compressed file with a `.map` extension.
+// It returns nil if the file does not need to be compressed.
+func CSS(ctx context.Context, file string, sourceMap bool) (*string, error) {
 	ext := path.Ext(file)
 	outfile := file[0:len(file)-len(ext)] + ".min.css"
 
 	// compressed file already exists, ignore
 	if _, err := os.Stat(outfile); err == nil {
 		log.Printf("%s already has a compressed version: %s\n", file, outfile)
-		return nil
+		return nil, nil
 	}
 
 	// Already minified, ignore
 	if strings.HasSuffix(file, ".min.css") {
-		return nil
+		return nil, nil
 	}
 
 	args := []string{
@@ -36,13 +39,18 @@ func CSS(ctx context.Context, file string) *string {
 		"-o", outfile,
 		file,
 	}
+	if sourceMap {
+		args = append(args, "--source-map", "--source-map-inline-sources")
+	}
 
-	cmd := exec.Command(cleanCSS, args...)
-	log.Printf("compress: run %s\n", cmd)
-
-	if _, err := cmd.CombinedOutput(); err != nil {
-		log.Printf("Failed to compress CSS: %v\n", err)
-		return nil
+	err := runMinifier(CLEANCSS, args...)
+	if err == nil && sourceMap {
+		err = relativizeSourceMap(outfile + ".map")
+	}
+	if err != nil {
+		os.Remove(outfile)
+		os.Remove(outfile + ".map")
+		return nil, errors.Wrapf(err, "could not minify %s", path.Base(file))
 	}
-	return &outfile
+	return &outfile, nil
 }
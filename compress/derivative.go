@@ -0,0 +1,100 @@
+package compress
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Image encoders of the derivatives
+var (
+	CWEBP   = "cwebp"
+	AVIFENC = "avifenc"
+)
+
+// ImageFormat is a modern image format derivatives of PNG and JPEG
+// images can be generated in.
+type ImageFormat struct {
+	Ext  string
+	bin  *string
+	args func(src, out string, lossless bool) []string
+}
+
+// WebP is the WebP format, lossless for PNG images.
+var WebP = ImageFormat{
+	Ext: ".webp",
+	bin: &CWEBP,
+	args: func(src, out string, lossless bool) []string {
+		args := []string{"-quiet", "-metadata", "icc"}
+		if lossless {
+			args = append(args, "-lossless")
+		} else {
+			args = append(args, "-q", "80")
+		}
+		return append(args, src, "-o", out)
+	},
+}
+
+// AVIF is the AVIF format, lossless for PNG images.
+var AVIF = ImageFormat{
+	Ext: ".avif",
+	bin: &AVIFENC,
+	args: func(src, out string, lossless bool) []string {
+		args := []string{"--jobs", "1", "--speed", "6"}
+		if lossless {
+			args = append(args, "--lossless")
+		}
+		return append(args, src, out)
+	},
+}
+
+// Derivative generates an image in another format next to a PNG or JPEG
+// image (ex. `logo.png` -> `logo.png.webp`), keeping the original. The
+// original extension is kept so that `logo.png` and `logo.jpg` do not
+// share their derivatives. It returns nil if the derivative is not
+// generated because it already exists, its encoder is not installed
+// or it is not smaller than the original.
+func Derivative(ctx context.Context, file string, format ImageFormat) (*string, error) {
+	outfile := file + format.Ext
+
+	// the package provides this format already, ignore
+	if _, err := os.Stat(outfile); err == nil {
+		log.Printf("%s already has a %s derivative: %s\n", file, format.Ext, outfile)
+		return nil, nil
+	}
+
+	bin, err := exec.LookPath(*format.bin)
+	if err != nil {
+		log.Printf("%s derivatives are not supported: %s\n", format.Ext, err)
+		return nil, nil
+	}
+
+	lossless := strings.ToLower(path.Ext(file)) == ".png"
+	cmd := exec.Command(bin, format.args(file, outfile, lossless)...)
+	log.Printf("compress: run %s\n", cmd)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outfile)
+		return nil, errors.Errorf("could not generate %s derivative of %s: %s: %s",
+			format.Ext, path.Base(file), err, strings.TrimSpace(string(out)))
+	}
+
+	original, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not stat original")
+	}
+	derivative, err := os.Stat(outfile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not stat derivative")
+	}
+	if derivative.Size() >= original.Size() {
+		log.Printf("%s derivative of %s is not smaller, ignoring\n", format.Ext, file)
+		os.Remove(outfile)
+		return nil, nil
+	}
+	return &outfile, nil
+}
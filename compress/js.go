@@ -2,58 +2,182 @@ package compress
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // Extensions the compression handle
 var (
 	UGLIFYJS = "/node_modules/uglify-js/bin/uglifyjs"
-	UGLIFYES = "/node_modules/uglify-es/bin/uglifyjs"
+	TERSER   = "/node_modules/terser/bin/terser"
+	ESBUILD  = "/node_modules/esbuild/bin/esbuild"
 )
 
-// Js performs a compression of the file.
-func Js(ctx context.Context, file string) *string {
+// MinifyOptions configures the minification of a JavaScript file.
+type MinifyOptions struct {
+	Mangle    bool
+	Compress  bool
+	Target    string // ECMAScript version (ex. es2018), empty for the minifier's default
+	SourceMap bool   // write the source map next to the output with a `.map` extension
+}
+
+// JsMinifier minifies JavaScript files.
+type JsMinifier interface {
+	// Name returns the name of the minifier, as configured in packages.
+	Name() string
+
+	// Minify minifies file into outfile.
+	Minify(ctx context.Context, file, outfile string, opts MinifyOptions) error
+}
+
+// JsMinifiers are the available minifiers by name.
+var JsMinifiers = map[string]JsMinifier{
+	"uglify":  uglify{},
+	"terser":  terser{},
+	"esbuild": esbuild{},
+}
+
+// DefaultJsMinifiers are the minifiers tried in order when a package
+// does not select one, terser supports the syntax uglify-js does not.
+var DefaultJsMinifiers = []string{"uglify", "terser"}
+
+// GetJsMinifier returns a minifier by name.
+func GetJsMinifier(name string) (JsMinifier, error) {
+	m, ok := JsMinifiers[name]
+	if !ok {
+		return nil, errors.Errorf("unknown minifier `%s`", name)
+	}
+	return m, nil
+}
+
+// Js performs a compression of the file, trying each minifier until one
+// succeeds. It returns nil if the file does not need to be compressed,
+// or an error describing the failure of each minifier.
+func Js(ctx context.Context, file string, minifiers []JsMinifier, opts MinifyOptions) (*string, error) {
 	ext := path.Ext(file)
 	outfile := file[0:len(file)-len(ext)] + ".min.js"
 
 	// compressed file already exists, ignore
 	if _, err := os.Stat(outfile); err == nil {
 		log.Printf("compressed file already exists: %s\n", outfile)
-		return nil
+		return nil, nil
 	}
 
 	// Already minified, ignore
 	if strings.HasSuffix(file, ".min.js") {
 		log.Printf("%s.min.js compressed file already exists\n", file)
-		return nil
+		return nil, nil
 	}
 
-	args := []string{
-		"--mangle",
-		"--compress",
-		"if_return=true",
-		"-o", outfile,
-		file,
+	failures := make([]string, 0)
+	for _, m := range minifiers {
+		err := m.Minify(ctx, file, outfile, opts)
+		if err == nil && opts.SourceMap {
+			err = relativizeSourceMap(outfile + ".map")
+		}
+		if err == nil {
+			return &outfile, nil
+		}
+		log.Printf("%s failed: %s\n", m.Name(), err)
+		failures = append(failures, fmt.Sprintf("%s: %s", m.Name(), err))
+
+		// do not publish a partial output
+		os.Remove(outfile)
+		os.Remove(outfile + ".map")
 	}
+	return nil, errors.Errorf("could not minify %s: %s", path.Base(file), strings.Join(failures, "; "))
+}
 
-	// try with uglifyjs, if it fails retry with uglifyes
-	cmd := exec.Command(UGLIFYJS, args...)
+// Runs a minifier, returning its output in the error if it fails.
+func runMinifier(bin string, args ...string) error {
+	cmd := exec.Command(bin, args...)
 	log.Printf("compress: run %s\n", cmd)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("failed with %s: %s\n", err, out)
-
-		cmd := exec.Command(UGLIFYES, args...)
-		log.Printf("compress: run %s\n", cmd)
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			log.Printf("failed with %s: %s\n", err, out)
-			return nil
-		}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Returns the uglify/terser option to write a source map, with the sources
+// relative to the file and the map next to the output.
+func sourceMapOption(file, outfile string) string {
+	return fmt.Sprintf("base='%s',url='%s',includeSources", path.Dir(file), path.Base(outfile)+".map")
+}
+
+// uglify-js, which only supports a subset of ES2015+.
+type uglify struct{}
+
+func (uglify) Name() string {
+	return "uglify"
+}
+
+func (uglify) Minify(ctx context.Context, file, outfile string, opts MinifyOptions) error {
+	args := make([]string, 0)
+	if opts.Mangle {
+		args = append(args, "--mangle")
+	}
+	if opts.Compress {
+		args = append(args, "--compress", "if_return=true")
+	}
+	args = append(args, "-o", outfile, file)
+	if opts.SourceMap {
+		args = append(args, "--source-map", sourceMapOption(file, outfile))
+	}
+	return runMinifier(UGLIFYJS, args...)
+}
+
+// terser, a fork of uglify-es supporting ES2015+.
+type terser struct{}
+
+func (terser) Name() string {
+	return "terser"
+}
+
+func (terser) Minify(ctx context.Context, file, outfile string, opts MinifyOptions) error {
+	args := []string{file}
+	if opts.Mangle {
+		args = append(args, "--mangle")
+	}
+	if opts.Compress {
+		args = append(args, "--compress")
+	}
+	if opts.Target != "" && opts.Target != "esnext" {
+		args = append(args, "--ecma", strings.TrimPrefix(opts.Target, "es"))
+	}
+	args = append(args, "-o", outfile)
+	if opts.SourceMap {
+		args = append(args, "--source-map", sourceMapOption(file, outfile))
+	}
+	return runMinifier(TERSER, args...)
+}
+
+// esbuild, which does not bundle the file.
+type esbuild struct{}
+
+func (esbuild) Name() string {
+	return "esbuild"
+}
+
+func (esbuild) Minify(ctx context.Context, file, outfile string, opts MinifyOptions) error {
+	args := []string{file, "--minify-whitespace", "--log-level=warning"}
+	if opts.Mangle {
+		args = append(args, "--minify-identifiers")
+	}
+	if opts.Compress {
+		args = append(args, "--minify-syntax")
+	}
+	if opts.Target != "" {
+		args = append(args, "--target="+opts.Target)
+	}
+	args = append(args, "--outfile="+outfile)
+	if opts.SourceMap {
+		args = append(args, "--sourcemap", "--sources-content=true")
 	}
-	return &outfile
+	return runMinifier(ESBUILD, args...)
 }
@@ -0,0 +1,152 @@
+package compress
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// RawManifestName is the name of the manifest listing the files published
+// raw, at the root of the output of a processed version, so that a raw file
+// whose extension is the one of a compressed variant is not taken for a
+// compressed variant. A package file with this name is not published.
+const RawManifestName = ".cdnjs-raw.json"
+
+// WriteRawManifest writes the manifest of the raw files of a directory.
+func WriteRawManifest(dir string, files []string) error {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal raw manifest")
+	}
+	if err := ioutil.WriteFile(path.Join(dir, RawManifestName), data, 0644); err != nil {
+		return errors.Wrap(err, "could not write raw manifest")
+	}
+	return nil
+}
+
+// ParseRawManifest parses a manifest of raw files into the set of their names.
+func ParseRawManifest(r io.Reader) (map[string]bool, error) {
+	var files []string
+	if err := json.NewDecoder(r).Decode(&files); err != nil {
+		return nil, errors.Wrap(err, "could not parse raw manifest")
+	}
+
+	raw := make(map[string]bool, len(files))
+	for _, file := range files {
+		raw[file] = true
+	}
+	return raw, nil
+}
+
+// ReadRawManifest reads the manifest of the raw files of a directory,
+// which has no raw file if the manifest does not exist.
+func ReadRawManifest(dir string) (map[string]bool, error) {
+	f, err := os.Open(path.Join(dir, RawManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]bool), nil
+		}
+		return nil, errors.Wrap(err, "could not open raw manifest")
+	}
+	defer f.Close()
+	return ParseRawManifest(f)
+}
+
+// OriginalName returns the name of the original file of a published file:
+// raw files keep their name, compressed variants lose their extension.
+// Returns false if the file is raw.
+func OriginalName(name string, raw map[string]bool) (string, bool) {
+	if raw[name] {
+		return name, false
+	}
+	return TrimExt(name)
+}
+
+// The content types, as sniffed by http.DetectContentType,
+// of the formats that are already compressed.
+var compressedTypes = map[string]bool{
+	"application/x-gzip":           true,
+	"application/x-rar-compressed": true,
+	"application/zip":              true,
+	"application/ogg":              true,
+	"audio/mpeg":                   true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+	"image/gif":                    true,
+	"image/jpeg":                   true,
+	"image/png":                    true,
+	"image/webp":                   true,
+	"video/mp4":                    true,
+	"video/webm":                   true,
+}
+
+// IsCompressedFormat determines from its first bytes if a file is in
+// an already compressed format (ex. PNG, JPEG, WOFF, ZIP or MP4).
+func IsCompressedFormat(src string) (bool, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return false, errors.Wrap(err, "could not open file")
+	}
+	defer f.Close()
+
+	// DetectContentType considers at most 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, errors.Wrap(err, "could not read file")
+	}
+
+	return compressedTypes[http.DetectContentType(head[:n])], nil
+}
+
+// EncodeFiles compresses the file src with each encoder into dest followed
+// by the encoder's extension. If any compressed file is not smaller than src,
+// the compressed files are removed and false is returned, src should
+// be published raw instead.
+func EncodeFiles(encoders []Encoder, src, dest string) (bool, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return false, errors.Wrap(err, "could not stat source file")
+	}
+
+	smaller := true
+	written := make([]string, 0, len(encoders))
+	for _, e := range encoders {
+		out := dest + e.Ext()
+		written = append(written, out)
+		if err := EncodeFile(e, src, out); err != nil {
+			removeAll(written)
+			return false, err
+		}
+		compressed, err := os.Stat(out)
+		if err != nil {
+			removeAll(written)
+			return false, errors.Wrap(err, "could not stat compressed file")
+		}
+		if compressed.Size() >= info.Size() {
+			smaller = false
+			break
+		}
+	}
+
+	if !smaller {
+		removeAll(written)
+	}
+	return smaller, nil
+}
+
+// Removes files, ignoring errors.
+func removeAll(files []string) {
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
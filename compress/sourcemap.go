@@ -0,0 +1,76 @@
+package compress
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Rewrites the sources of a source map relative to the map, the minifiers
+// may reference the sources by their absolute path in the workspace.
+// Sources outside of the map's directory are referenced by their name.
+func relativizeSourceMap(mapfile string) error {
+	content, err := ioutil.ReadFile(mapfile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not read source map")
+	}
+
+	var sourceMap map[string]json.RawMessage
+	if err := json.Unmarshal(content, &sourceMap); err != nil {
+		return errors.Wrap(err, "could not parse source map")
+	}
+
+	var sourceRoot string
+	if raw, ok := sourceMap["sourceRoot"]; ok {
+		if err := json.Unmarshal(raw, &sourceRoot); err != nil {
+			return errors.Wrap(err, "could not parse source root")
+		}
+		if filepath.IsAbs(strings.TrimPrefix(sourceRoot, "file://")) {
+			delete(sourceMap, "sourceRoot")
+		} else {
+			sourceRoot = ""
+		}
+	}
+
+	var sources []string
+	if raw, ok := sourceMap["sources"]; ok {
+		if err := json.Unmarshal(raw, &sources); err != nil {
+			return errors.Wrap(err, "could not parse sources")
+		}
+	}
+
+	dir := filepath.Dir(mapfile)
+	for i, source := range sources {
+		source = strings.TrimPrefix(source, "file://")
+		if sourceRoot != "" && !filepath.IsAbs(source) {
+			source = filepath.Join(strings.TrimPrefix(sourceRoot, "file://"), source)
+		}
+		if !filepath.IsAbs(source) {
+			continue
+		}
+		rel, err := filepath.Rel(dir, source)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(source)
+		}
+		sources[i] = filepath.ToSlash(rel)
+	}
+
+	raw, err := json.Marshal(sources)
+	if err != nil {
+		return errors.Wrap(err, "could not encode sources")
+	}
+	sourceMap["sources"] = raw
+
+	out, err := json.Marshal(sourceMap)
+	if err != nil {
+		return errors.Wrap(err, "could not encode source map")
+	}
+	return ioutil.WriteFile(mapfile, out, 0644)
+}
@@ -0,0 +1,343 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SVGPrecision is the number of decimals kept in path data.
+const SVGPrecision = 3
+
+// Namespaces of the editors' metadata, removed from SVG files.
+var svgEditorNamespaces = map[string]bool{
+	"inkscape": true,
+	"sodipodi": true,
+	"sketch":   true,
+}
+
+// Elements in which whitespace is significant.
+var svgPreserveSpace = map[string]bool{
+	"text":     true,
+	"tspan":    true,
+	"textPath": true,
+	"style":    true,
+	"script":   true,
+}
+
+// Attributes containing a list of coordinates, by element.
+var svgCoordinates = map[string]string{
+	"path":     "d",
+	"polygon":  "points",
+	"polyline": "points",
+}
+
+// SVG performs a compression of the file. It removes comments, metadata and
+// whitespace between elements, and reduces the precision of path data.
+// It returns nil if the file does not need to be compressed.
+func SVG(ctx context.Context, file string) (*string, error) {
+	ext := path.Ext(file)
+	outfile := file[0:len(file)-len(ext)] + ".min.svg"
+
+	// compressed file already exists, ignore
+	if _, err := os.Stat(outfile); err == nil {
+		log.Printf("%s already has a compressed version: %s\n", file, outfile)
+		return nil, nil
+	}
+
+	// Already minified, ignore
+	if strings.HasSuffix(file, ".min.svg") {
+		return nil, nil
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open file")
+	}
+	defer in.Close()
+
+	var out bytes.Buffer
+	if err := OptimizeSVG(&out, in); err != nil {
+		return nil, errors.Wrapf(err, "could not minify %s", path.Base(file))
+	}
+
+	if err := ioutil.WriteFile(outfile, out.Bytes(), 0644); err != nil {
+		return nil, errors.Wrap(err, "could not write file")
+	}
+	log.Printf("compress: svg %s -> %s\n", file, outfile)
+	return &outfile, nil
+}
+
+// OptimizeSVG writes an optimized version of the SVG document r to w.
+// Documents declaring entities are not supported.
+func OptimizeSVG(w io.Writer, r io.Reader) error {
+	d := xml.NewDecoder(r)
+
+	// whether whitespace is significant, by open element
+	stack := make([]bool, 0)
+	skipDepth := 0 // depth of the removed element being skipped
+
+	var out bytes.Buffer
+	var pending *xml.StartElement // start element which may be self-closing
+
+	flush := func() {
+		if pending != nil {
+			writeStart(&out, pending)
+			out.WriteString(">")
+			pending = nil
+		}
+	}
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid SVG")
+		}
+
+		if skipDepth > 0 {
+			switch tok.(type) {
+			case xml.StartElement:
+				skipDepth++
+			case xml.EndElement:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			flush()
+			if t.Name.Local == "metadata" || svgEditorNamespaces[t.Name.Space] {
+				skipDepth = 1
+				continue
+			}
+
+			preserveSpace := len(stack) > 0 && stack[len(stack)-1]
+			preserveSpace = preserveSpace || svgPreserveSpace[t.Name.Local]
+
+			attrs := make([]xml.Attr, 0, len(t.Attr))
+			for _, attr := range t.Attr {
+				if svgEditorNamespaces[attr.Name.Space] {
+					continue
+				}
+				if attr.Name.Space == "xmlns" && svgEditorNamespaces[attr.Name.Local] {
+					continue
+				}
+				if attr.Name.Space == "xml" && attr.Name.Local == "space" {
+					preserveSpace = attr.Value == "preserve"
+				}
+				if name, ok := svgCoordinates[t.Name.Local]; ok && attr.Name.Space == "" && attr.Name.Local == name {
+					attr.Value = reducePrecision(attr.Value, SVGPrecision)
+				}
+				attrs = append(attrs, attr)
+			}
+
+			start := xml.StartElement{Name: t.Name, Attr: attrs}
+			pending = &start
+			stack = append(stack, preserveSpace)
+		case xml.EndElement:
+			if pending != nil {
+				writeStart(&out, pending)
+				out.WriteString("/>")
+				pending = nil
+			} else {
+				out.WriteString("</" + qualifiedName(t.Name) + ">")
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			if !stack[len(stack)-1] && len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			flush()
+			escapeSVG(&out, string(t), false)
+		case xml.Comment:
+			// removed
+		case xml.ProcInst:
+			// only an UTF-8 XML declaration can be removed
+			utf8 := false
+			if t.Target == "xml" {
+				var err error
+				if utf8, err = isUTF8Declaration(string(t.Inst)); err != nil {
+					return err
+				}
+			}
+			if !utf8 {
+				flush()
+				out.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+			}
+		case xml.Directive:
+			if bytes.Contains(t, []byte("<!ENTITY")) {
+				return errors.New("SVG with entities are not supported")
+			}
+			// DOCTYPE without entities
+		}
+	}
+
+	if len(stack) > 0 {
+		return errors.New("invalid SVG: unclosed elements")
+	}
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func isUTF8Declaration(inst string) (bool, error) {
+	i := strings.Index(inst, "encoding=")
+	if i < 0 {
+		return true, nil
+	}
+	fields := strings.Fields(inst[i+len("encoding="):])
+	if len(fields) == 0 {
+		return false, errors.New("invalid SVG: empty encoding in the XML declaration")
+	}
+	encoding := strings.Trim(fields[0], `"'`)
+	return strings.EqualFold(encoding, "utf-8"), nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func writeStart(out *bytes.Buffer, start *xml.StartElement) {
+	out.WriteString("<" + qualifiedName(start.Name))
+	for _, attr := range start.Attr {
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		escapeSVG(out, attr.Value, true)
+		out.WriteString(`"`)
+	}
+}
+
+func escapeSVG(out *bytes.Buffer, s string, attr bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			out.WriteString("&amp;")
+		case r == '<':
+			out.WriteString("&lt;")
+		case r == '>':
+			out.WriteString("&gt;")
+		case r == '"' && attr:
+			out.WriteString("&quot;")
+		case (r == '\n' || r == '\t' || r == '\r') && attr:
+			out.WriteString(" ")
+		default:
+			out.WriteRune(r)
+		}
+	}
+}
+
+// Rounds the numbers of a list of coordinates (ex. path data) to a number
+// of decimals, keeping the separators between them. The flags of the arc
+// commands are single digits, which may not be separated from the next
+// number (ex. `a1 1 0 01.5.5`), they are kept as is.
+func reducePrecision(data string, decimals int) string {
+	var out strings.Builder
+	scale := math.Pow(10, float64(decimals))
+
+	var command byte // current path command
+	arg := 0         // index of the next argument of the command
+
+	for i := 0; i < len(data); {
+		if isArcFlag(command, arg) && (data[i] == '0' || data[i] == '1') {
+			out.WriteByte(data[i])
+			arg++
+			i++
+			continue
+		}
+
+		end := scanNumber(data, i)
+		if end == i {
+			if c := data[i]; (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+				command = c
+				arg = 0
+			}
+			out.WriteByte(data[i])
+			i++
+			continue
+		}
+		arg++
+
+		number := data[i:end]
+		if v, err := strconv.ParseFloat(number, 64); err == nil {
+			rounded := strconv.FormatFloat(math.Round(v*scale)/scale, 'f', -1, 64)
+			if rounded == "-0" {
+				rounded = "0"
+			}
+			if len(rounded) < len(number) {
+				number = rounded
+			}
+		}
+		out.WriteString(number)
+
+		// `1.5.5` is `1.5 .5`, rounding the first number to `2` must
+		// not merge it with the next one
+		if end < len(data) && data[end] == '.' && !strings.ContainsAny(number, ".eE") {
+			out.WriteByte(' ')
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// Returns whether the argument of a path command is an arc flag, the
+// arguments of an arc are `rx ry rotation large-arc sweep x y`.
+func isArcFlag(command byte, arg int) bool {
+	return (command == 'a' || command == 'A') && (arg%7 == 3 || arg%7 == 4)
+}
+
+// Returns the end of the number starting at i, or i if there is none.
+func scanNumber(s string, i int) int {
+	j := i
+	if j < len(s) && (s[j] == '-' || s[j] == '+') {
+		j++
+	}
+	digits := 0
+	for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+		j++
+		digits++
+	}
+	if j < len(s) && s[j] == '.' {
+		j++
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return i
+	}
+	if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+		k := j + 1
+		if k < len(s) && (s[k] == '-' || s[k] == '+') {
+			k++
+		}
+		if k < len(s) && s[k] >= '0' && s[k] <= '9' {
+			for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+				k++
+			}
+			j = k
+		}
+	}
+	return j
+}
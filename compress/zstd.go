@@ -0,0 +1,57 @@
+package compress
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// DefaultZstdLevel is the default zstd level, the highest
+// level not requiring the `--ultra` flag.
+const DefaultZstdLevel = 19
+
+// Zstd compresses streams with zstd at a level between 1 and 19, which is
+// mapped to the closest level of the pure-Go encoder.
+type Zstd struct {
+	Level int
+}
+
+// Ext returns the extension of zstd compressed files.
+func (z Zstd) Ext() string {
+	return ".zst"
+}
+
+// Encode writes the zstd compressed content of r to w.
+func (z Zstd) Encode(w io.Writer, r io.Reader) error {
+	if z.Level < 1 || z.Level > DefaultZstdLevel {
+		return errors.Errorf("invalid zstd level %d", z.Level)
+	}
+
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(z.Level)))
+	if err != nil {
+		return errors.Wrap(err, "could not create zstd writer")
+	}
+	if _, err := io.Copy(zw, r); err != nil {
+		zw.Close()
+		return errors.Wrap(err, "could not compress")
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(err, "could not close zstd writer")
+	}
+	return nil
+}
+
+// UnZstd writes the zstd uncompressed content of r to w.
+func UnZstd(w io.Writer, r io.Reader) error {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "could not create zstd reader")
+	}
+	defer zr.Close()
+
+	if _, err := io.Copy(w, zr); err != nil {
+		return errors.Wrap(err, "could not uncompress")
+	}
+	return nil
+}
@@ -12,15 +12,16 @@ import (
 	"os"
 	"path/filepath"
 
-	cloudflare "github.com/cloudflare/cloudflare-go"
 	"github.com/pkg/errors"
 
 	"github.com/cdnjs/tools/algolia"
 	"github.com/cdnjs/tools/audit"
+	"github.com/cdnjs/tools/compress"
 	"github.com/cdnjs/tools/gcp"
 	"github.com/cdnjs/tools/kv"
 	"github.com/cdnjs/tools/packages"
 	"github.com/cdnjs/tools/sentry"
+	"github.com/cdnjs/tools/untar"
 )
 
 var (
@@ -29,14 +30,9 @@ var (
 	CF_ACCOUNT_ID = os.Getenv("CF_ACCOUNT_ID")
 )
 
-func getExistingVersionsFromAggregatedMetadata(p *packages.Package) ([]string, error) {
-	cfapi, err := cloudflare.NewWithAPIToken(KV_TOKEN, cloudflare.UsingAccount(CF_ACCOUNT_ID))
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create cloudflare API client")
-	}
-
+func getExistingVersionsFromAggregatedMetadata(ctx context.Context, store kv.Store, p *packages.Package) ([]string, error) {
 	log.Printf("Fetching versions from aggregated metadata for: `%s`\n", *p.Name)
-	versions, err := kv.GetVersionsFromAggregatedMetadata(cfapi, *p.Name)
+	versions, err := kv.GetVersionsFromAggregatedMetadata(ctx, store, *p.Name)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get versions")
 	}
@@ -63,22 +59,33 @@ func Invoke(ctx context.Context, e gcp.GCSEvent) error {
 	if err := json.Unmarshal([]byte(configStr), &pkg); err != nil {
 		return fmt.Errorf("could not decode config: %v", err)
 	}
+	store, err := kv.NewCloudflareStoreFromEnv(KV_TOKEN, CF_ACCOUNT_ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloudflare API client")
+	}
+
 	// update package version with latest
-	versions, err := getExistingVersionsFromAggregatedMetadata(pkg)
+	versions, err := getExistingVersionsFromAggregatedMetadata(ctx, store, pkg)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve existing versions: %s", err)
 	}
+
+	// kv-pump removes the version concurrently, it may still be listed
+	reason, isTakedown := e.GetTakedown()
+	if isTakedown {
+		log.Printf("%s: takedown %s: %s\n", pkgName, currVersion, reason)
+		versions = removeVersion(versions, currVersion)
+	}
 	archive, err := gcp.ReadObject(ctx, e.Bucket, e.Name)
 	if err != nil {
 		return fmt.Errorf("could not read object: %v", err)
 	}
 
 	sris := make(map[string]string)
-	files := make([]string, 0)
+	names := make([]string, 0)
+	raw := make(map[string]bool)
 	onFile := func(name string, r io.Reader) error {
 		ext := filepath.Ext(name)
-		// remove leading slash
-		name = name[1:]
 		filename := name[0 : len(name)-len(ext)]
 
 		if ext == ".sri" {
@@ -87,17 +94,36 @@ func Invoke(ctx context.Context, e gcp.GCSEvent) error {
 				return errors.Wrap(err, "could not read file")
 			}
 			sris[filename] = string(content)
+			return nil
 		}
-
-		if ext == ".gz" || ext == ".woff2" {
-			files = append(files, filename)
+		if name == compress.RawManifestName {
+			files, err := compress.ParseRawManifest(r)
+			if err != nil {
+				return err
+			}
+			for file := range files {
+				raw[file] = true
+			}
+			return nil
 		}
+		names = append(names, name)
 		return nil
 	}
-	if err := gcp.Inflate(bytes.NewReader(archive), onFile); err != nil {
+	if err := untar.Inflate(bytes.NewReader(archive), onFile); err != nil {
 		return fmt.Errorf("could not inflate archive: %s", err)
 	}
 
+	// files are either compressed (.br, .gz, .zst) or raw
+	files := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		original, _ := compress.OriginalName(name, raw)
+		if !seen[original] {
+			seen[original] = true
+			files = append(files, original)
+		}
+	}
+
 	log.Printf("%s: %d files, SRIs: %s\n", pkgName, len(files), sris)
 
 	if len(files) > 0 {
@@ -107,6 +133,15 @@ func Invoke(ctx context.Context, e gcp.GCSEvent) error {
 
 	// Update package's current version and fix filename if needed
 	pkg.Version = packages.GetLatestStableVersion(versions)
+
+	// the archive of a takedown is empty, use the SRIs of the latest version
+	if isTakedown && pkg.Version != nil {
+		sris, err = kv.GetSRIManifest(ctx, store, *pkg.Name, *pkg.Version)
+		if err != nil {
+			log.Printf("%s: could not get SRIs of %s: %s\n", pkgName, *pkg.Version, err)
+			sris = make(map[string]string)
+		}
+	}
 	if err := packages.UpdateFilenameIfMissing(ctx, pkg, files); err != nil {
 		return errors.Wrap(err, "failed to fix missing filename")
 	}
@@ -129,6 +164,17 @@ func Invoke(ctx context.Context, e gcp.GCSEvent) error {
 	return nil
 }
 
+// Removes a version from a list of versions.
+func removeVersion(versions []string, version string) []string {
+	out := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if v != version {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func printStrPtr(v *string) string {
 	if v == nil {
 		return "<nil>"
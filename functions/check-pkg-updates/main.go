@@ -1,6 +1,7 @@
 package check_pkg_updates
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
@@ -14,7 +15,6 @@ import (
 	"github.com/cdnjs/tools/sentry"
 	"github.com/cdnjs/tools/util"
 
-	cloudflare "github.com/cloudflare/cloudflare-go"
 	"github.com/pkg/errors"
 )
 
@@ -29,18 +29,24 @@ type APIPackage struct {
 	Versions []string `json:"versions"`
 }
 
-func getExistingVersions(p *packages.Package) ([]string, error) {
-	cfapi, err := cloudflare.NewWithAPIToken(KV_TOKEN, cloudflare.UsingAccount(CF_ACCOUNT_ID))
+// Gets the versions of a package in KV, and the versions which were taken down.
+func getExistingVersions(p *packages.Package) ([]string, []string, error) {
+	store, err := kv.NewCloudflareStoreFromEnv(KV_TOKEN, CF_ACCOUNT_ID)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create cloudflare API client")
+		return nil, nil, errors.Wrap(err, "failed to create cloudflare API client")
 	}
 
-	versions, err := kv.GetVersions(cfapi, *p.Name)
+	versions, err := kv.GetVersions(context.Background(), store, *p.Name)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get verions")
+		return nil, nil, errors.Wrap(err, "failed to get verions")
 	}
 
-	return versions, nil
+	takenDown, err := kv.GetTakenDownVersions(context.Background(), store, *p.Name)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get taken down versions")
+	}
+
+	return versions, takenDown, nil
 }
 
 func Invoke(w http.ResponseWriter, r *http.Request) {
@@ -103,7 +109,7 @@ func checkPackage(pkg *packages.Package) error {
 	}
 
 	switch src {
-	case "npm", "git":
+	case "npm", "git", "github-release":
 		{
 			if err := updatePackage(ctx, pkg, src); err != nil {
 				return errors.Wrap(err, "failed to update package via "+src)
@@ -19,13 +19,14 @@ import (
 )
 
 func updatePackage(ctx context.Context, pkg *packages.Package, src string) error {
-	existingVersionSet, err := getExistingVersions(pkg)
+	existingVersionSet, takenDown, err := getExistingVersions(pkg)
 	if err != nil {
 		return errors.Wrap(err, "could not detect existing versions")
 	}
 	log.Printf("%s: existing versions: %s\n", *pkg.Name, strings.Join(existingVersionSet, ","))
 
 	var versions []version.Version
+	var skipped []npm.SkippedVersion
 
 	switch src {
 	case "git":
@@ -33,17 +34,30 @@ func updatePackage(ctx context.Context, pkg *packages.Package, src string) error
 		if err != nil {
 			return errors.Wrap(err, "failed to get git versions")
 		}
+	case "github-release":
+		versions, err = git.GetReleaseVersions(ctx, pkg.Autoupdate)
+		if err != nil {
+			return errors.Wrap(err, "failed to get GitHub release versions")
+		}
 	case "npm":
-		versions, _ = npm.GetVersions(ctx, pkg.Autoupdate)
+		versions, skipped, _, err = npm.GetVersions(ctx, pkg.Autoupdate)
+		if err != nil {
+			return errors.Wrap(err, "failed to get npm versions")
+		}
 	default:
 		panic("unreachable")
 	}
 
+	// the versions which were taken down are not imported again
+	versions = withoutVersions(versions, takenDown)
+
 	lastExistingVersion, _ := version.GetMostRecentExistingVersion(ctx, existingVersionSet, versions)
 
 	if lastExistingVersion != nil {
 		log.Printf("%s: last existing version: %s\n", *pkg.Name, lastExistingVersion.Version)
 
+		auditSkippedVersions(ctx, pkg, skipped, lastExistingVersion)
+
 		versionDiff := version.VersionDiff(versions, existingVersionSet)
 		sort.Sort(version.ByDate(versionDiff))
 
@@ -89,6 +103,40 @@ func updatePackage(ctx context.Context, pkg *packages.Package, src string) error
 	return nil
 }
 
+// Removes versions from a list of versions.
+func withoutVersions(versions []version.Version, removed []string) []version.Version {
+	if len(removed) == 0 {
+		return versions
+	}
+	set := make(map[string]bool, len(removed))
+	for _, v := range removed {
+		set[v] = true
+	}
+
+	out := make([]version.Version, 0, len(versions))
+	for _, v := range versions {
+		if set[v.Version] {
+			log.Printf("%s was taken down, ignoring\n", v.Version)
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// Audits the skipped versions that would otherwise have been imported.
+func auditSkippedVersions(ctx context.Context, pkg *packages.Package,
+	skipped []npm.SkippedVersion, lastExistingVersion *version.Version) {
+	for _, v := range skipped {
+		if !v.Date.After(lastExistingVersion.Date) {
+			continue
+		}
+		if err := audit.SkippedVersion(ctx, *pkg.Name, v.Version, v.Reason); err != nil {
+			log.Printf("%s: failed to audit skipped version %s: %s\n", *pkg.Name, v.Version, err)
+		}
+	}
+}
+
 func DoUpdate(ctx context.Context, pkg *packages.Package, versions []version.Version) error {
 	if len(versions) == 0 {
 		return nil
@@ -97,9 +145,22 @@ func DoUpdate(ctx context.Context, pkg *packages.Package, versions []version.Ver
 	v := versions[0]
 
 	log.Printf("%s: new version detected: %s\n", *pkg.Name, v.Version)
-	tarball := version.DownloadTar(ctx, v)
+	tarball, err := version.DownloadTar(ctx, v)
+	if err != nil {
+		if err := audit.DownloadFailed(ctx, *pkg.Name, v.Version, err); err != nil {
+			log.Printf("%s: failed to audit download failure: %s\n", *pkg.Name, err)
+		}
+		return errors.Wrap(err, "could not download tarball")
+	}
+	defer tarball.Close()
 	if err := gcp.AddIncomingFile(path.Base(v.Tarball), tarball, pkg, v); err != nil {
-		return errors.Wrap(err, "could not store in GCS: %s")
+		// the tarball is streamed to GCS, its download may fail while storing it
+		if isDownloadError(err) {
+			if err := audit.DownloadFailed(ctx, *pkg.Name, v.Version, err); err != nil {
+				log.Printf("%s: failed to audit download failure: %s\n", *pkg.Name, err)
+			}
+		}
+		return errors.Wrap(err, "could not store in GCS")
 	}
 
 	if err := audit.NewVersionDetected(ctx, *pkg.Name, v.Version); err != nil {
@@ -108,3 +169,14 @@ func DoUpdate(ctx context.Context, pkg *packages.Package, versions []version.Ver
 
 	return nil
 }
+
+// Returns if an error comes from the download of a tarball,
+// rather than from storing it.
+func isDownloadError(err error) bool {
+	switch errors.Cause(err).(type) {
+	case version.StatusError, version.SizeError, version.IntegrityError:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,19 +1,41 @@
 module github.com/cdnjs/tools/functions/force-update
 
-go 1.16
+go 1.22
+
+require github.com/cdnjs/tools v0.0.0-00010101000000-000000000000
 
 require (
 	cloud.google.com/go v0.81.0 // indirect
 	cloud.google.com/go/storage v1.15.0 // indirect
-	github.com/cdnjs/tools v0.0.0-00010101000000-000000000000
-	github.com/cloudflare/cloudflare-go v0.16.0 // indirect
-	github.com/dlclark/regexp2 v1.2.0 // indirect
+	github.com/agnivade/levenshtein v1.1.1 // indirect
+	github.com/algolia/algoliasearch-client-go/v3 v3.4.0 // indirect
+	github.com/andybalholm/brotli v1.0.4 // indirect
+	github.com/blang/semver v3.5.1+incompatible // indirect
+	github.com/cloudflare/cloudflare-go v0.12.1 // indirect
+	github.com/getsentry/sentry-go v0.6.1 // indirect
 	github.com/gobwas/glob v0.2.3 // indirect
-	github.com/pachyderm/ohmyglob v0.0.0-20190808212558-a8e61fd76805 // indirect
+	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/google/go-github v17.0.0+incompatible // indirect
+	github.com/google/go-querystring v1.0.0 // indirect
+	github.com/googleapis/gax-go/v2 v2.0.5 // indirect
+	github.com/karrick/godirwalk v1.15.6 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
+	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
+	go.opencensus.io v0.23.0 // indirect
+	golang.org/x/net v0.0.0-20210326060303-6b1517762897 // indirect
 	golang.org/x/oauth2 v0.0.0-20210413134643-5e61552d6c78 // indirect
+	golang.org/x/sys v0.0.0-20210412220455-f1c623a9e750 // indirect
+	golang.org/x/text v0.3.5 // indirect
+	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba // indirect
 	google.golang.org/api v0.45.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20210423144448-3a41ef94ed2b // indirect
 	google.golang.org/grpc v1.37.0 // indirect
+	google.golang.org/protobuf v1.26.0 // indirect
 )
 
 replace github.com/cdnjs/tools => ../../
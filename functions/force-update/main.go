@@ -6,16 +6,41 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"path"
 
 	"github.com/cdnjs/tools/audit"
 	"github.com/cdnjs/tools/gcp"
 	"github.com/cdnjs/tools/git"
+	"github.com/cdnjs/tools/kv"
 	"github.com/cdnjs/tools/npm"
 	"github.com/cdnjs/tools/packages"
 	"github.com/cdnjs/tools/version"
 )
 
+var (
+	KV_TOKEN      = os.Getenv("KV_TOKEN")
+	CF_ACCOUNT_ID = os.Getenv("CF_ACCOUNT_ID")
+)
+
+// Returns if a version of a package was taken down.
+func isTakenDown(ctx context.Context, pkgName, v string) (bool, error) {
+	store, err := kv.NewCloudflareStoreFromEnv(KV_TOKEN, CF_ACCOUNT_ID)
+	if err != nil {
+		return false, err
+	}
+	takenDown, err := kv.GetTakenDownVersions(ctx, store, pkgName)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range takenDown {
+		if t == v {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 type UpdateReq struct {
 	Pkg     string `json:"package"`
 	Version string `json:"version"`
@@ -37,6 +62,17 @@ func Invoke(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := context.Background()
 
+	takenDown, err := isTakenDown(ctx, d.Pkg, d.Version)
+	if err != nil {
+		http.Error(w, "failed to get taken down versions", 500)
+		fmt.Println(err)
+		return
+	}
+	if takenDown {
+		http.Error(w, fmt.Sprintf("version `%s` was taken down", d.Version), 400)
+		return
+	}
+
 	for _, pkg := range list {
 		if *pkg.Name == d.Pkg {
 			src := *pkg.Autoupdate.Source
@@ -49,8 +85,20 @@ func Invoke(w http.ResponseWriter, r *http.Request) {
 					fmt.Println(err)
 					return
 				}
+			case "github-release":
+				versions, err = git.GetReleaseVersionsWithLimit(ctx, pkg.Autoupdate, 100)
+				if err != nil {
+					http.Error(w, "failed to fetch versions", 500)
+					fmt.Println(err)
+					return
+				}
 			case "npm":
-				versions, _ = npm.GetVersions(ctx, pkg.Autoupdate)
+				versions, _, _, err = npm.GetVersions(ctx, pkg.Autoupdate)
+				if err != nil {
+					http.Error(w, "failed to fetch versions", 500)
+					fmt.Println(err)
+					return
+				}
 			default:
 				panic("unreachable")
 			}
@@ -72,7 +120,13 @@ func Invoke(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, msg, 500)
 				return
 			}
-			tarball := version.DownloadTar(ctx, *targetVersion)
+			tarball, err := version.DownloadTar(ctx, *targetVersion)
+			if err != nil {
+				http.Error(w, "failed to download tarball", 500)
+				fmt.Println(err)
+				return
+			}
+			defer tarball.Close()
 			if err := gcp.AddIncomingFile(path.Base(targetVersion.Tarball), tarball, pkg, *targetVersion); err != nil {
 				log.Fatalf("could not store in GCS: %s", err)
 			}
@@ -3,6 +3,7 @@ package kv_pump
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	b64 "encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,24 +12,25 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cdnjs/tools/audit"
+	"github.com/cdnjs/tools/compress"
 	"github.com/cdnjs/tools/gcp"
 	"github.com/cdnjs/tools/kv"
 	"github.com/cdnjs/tools/packages"
 	"github.com/cdnjs/tools/sentry"
+	"github.com/cdnjs/tools/untar"
 
-	cloudflare "github.com/cloudflare/cloudflare-go"
 	"github.com/pkg/errors"
 )
 
 var (
-	KV_TOKEN              = os.Getenv("KV_TOKEN")
-	CF_ACCOUNT_ID         = os.Getenv("CF_ACCOUNT_ID")
-	FILES_KV_NAMESPACE_ID = os.Getenv("FILES_KV_NAMESPACE_ID")
-	SRI_KV_NAMESPACE      = os.Getenv("WORKERS_KV_SRIS_NAMESPACE_ID")
+	KV_TOKEN      = os.Getenv("KV_TOKEN")
+	CF_ACCOUNT_ID = os.Getenv("CF_ACCOUNT_ID")
 )
 
 func Invoke(ctx context.Context, e gcp.GCSEvent) error {
@@ -44,27 +46,85 @@ func Invoke(ctx context.Context, e gcp.GCSEvent) error {
 		return fmt.Errorf("could not decode config: %v", err)
 	}
 
+	store, err := kv.NewCloudflareStoreFromEnv(KV_TOKEN, CF_ACCOUNT_ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloudflare API client")
+	}
+
+	if reason, ok := e.GetTakedown(); ok {
+		return takedown(ctx, store, pkgName, version, reason)
+	}
+
 	archive, err := gcp.ReadObject(ctx, e.Bucket, e.Name)
 	if err != nil {
 		return fmt.Errorf("could not read object: %v", err)
 	}
 
-	cfapi, err := cloudflare.NewWithAPIToken(KV_TOKEN, cloudflare.UsingAccount(CF_ACCOUNT_ID))
+	sris, kvKeys, err := publish(ctx, store, pkgName, version, configStr, archive)
 	if err != nil {
-		return errors.Wrap(err, "failed to create cloudflare API client")
+		return err
 	}
 
+	if err := audit.WroteKV(ctx, pkgName, version, sris, kvKeys, string(configStr)); err != nil {
+		log.Printf("failed to audit: %s\n", err)
+	}
+
+	return nil
+}
+
+// Removes a version from KV.
+func takedown(ctx context.Context, store kv.Store, pkgName, version, reason string) error {
+	log.Printf("%s: takedown %s: %s\n", pkgName, version, reason)
+	res, err := kv.TakedownVersion(ctx, store, pkgName, version)
+	if err != nil {
+		return fmt.Errorf("failed to takedown version: %s", err)
+	}
+
+	if err := audit.TookDownVersion(ctx, pkgName, version, reason, res.DeletedKeys, res.LatestVersion); err != nil {
+		log.Printf("failed to audit: %s\n", err)
+	}
+	return nil
+}
+
+// The steps of a publication, in the order they are applied. The files and
+// their SRIs are written before the version entry, the aggregated metadata
+// and the package entry, so that a visible version is complete.
+const (
+	filesStep      = "files"
+	srisStep       = "sris"
+	versionStep    = "version"
+	aggregatedStep = "aggregated-metadata"
+	packageStep    = "package"
+)
+
+// Publishes the files of a processed archive to KV, and then updates the
+// SRI, version, aggregated metadata and package entries. The publication is
+// recorded in a manifest, so that a retry resumes an interrupted publication.
+// Returns the SRIs and the file keys of the version.
+func publish(ctx context.Context, store kv.Store, pkgName, version string,
+	configStr []byte, archive []byte) (map[string]string, []string, error) {
 	var pairs []kv.WriteRequest
 	kvKeys := make([]string, 0)
 	sris := make(map[string]string)
 	kvfiles := make([]string, 0)
+	raw := make(map[string]bool)
+	pairsByName := make(map[string]*kv.ConsumableWriteRequest)
 
 	onFile := func(name string, r io.Reader) error {
 		ext := filepath.Ext(name)
-		// remove leading slash
-		name = name[1:]
 		key := fmt.Sprintf("%s/%s/%s", pkgName, version, name)
 
+		if name == compress.RawManifestName {
+			files, err := compress.ParseRawManifest(r)
+			if err != nil {
+				return err
+			}
+			for file := range files {
+				raw[file] = true
+			}
+			return nil
+		}
+
 		content, err := ioutil.ReadAll(r)
 		if err != nil {
 			return errors.Wrap(err, "could not read file")
@@ -76,76 +136,125 @@ func Invoke(ctx context.Context, e gcp.GCSEvent) error {
 			return nil
 		}
 
-		if ext == ".gz" || ext == ".br" || ext == ".woff2" {
-			kvKeys = append(kvKeys, key)
-			kvfiles = append(kvfiles, name)
+		// compressed variants (.br, .gz, .zst) and raw files, which
+		// were not smaller once compressed
+		kvKeys = append(kvKeys, key)
+		kvfiles = append(kvfiles, name)
 
-			meta := newMetadata(len(content))
-			writePair := &kv.ConsumableWriteRequest{
-				Key:   key,
-				Name:  key,
-				Value: content,
-				Meta:  meta,
-			}
-			pairs = append(pairs, writePair)
+		writePair := &kv.ConsumableWriteRequest{
+			Key:   key,
+			Name:  key,
+			Value: content,
+			Meta:  newMetadata(content),
 		}
+		pairs = append(pairs, writePair)
+		pairsByName[name] = writePair
 		return nil
 	}
-	if err := gcp.Inflate(bytes.NewReader(archive), onFile); err != nil {
-		return fmt.Errorf("could not inflate archive: %s", err)
+	if err := untar.Inflate(bytes.NewReader(archive), onFile); err != nil {
+		return nil, nil, fmt.Errorf("could not inflate archive: %s", err)
 	}
 
-	if len(pairs) > 0 {
-		_, err = kv.EncodeAndWriteKVBulk(ctx, cfapi, pairs, FILES_KV_NAMESPACE_ID, false)
-		if err != nil {
-			return fmt.Errorf("failed to write KV: %s", err)
+	// the raw manifest may follow the files in the archive
+	for name := range raw {
+		if pair, ok := pairsByName[name]; ok {
+			pair.Meta.Raw = true
 		}
-	} else {
-		log.Printf("%s: no files to publish\n", pkgName)
 	}
 
-	newFiles := cleanNewKVFiles(kvfiles)
+	newFiles := cleanNewKVFiles(kvfiles, raw)
 
 	pkg := new(packages.Package)
 	if err := json.Unmarshal([]byte(configStr), &pkg); err != nil {
-		return fmt.Errorf("failed to parse config: %s", err)
+		return nil, nil, fmt.Errorf("failed to parse config: %s", err)
 	}
 
-	if err := updateVersions(ctx, cfapi, pkg, version, newFiles); err != nil {
-		return fmt.Errorf("failed to update versions: %s", err)
+	manifest := kv.NewPublishManifest(pkgName, version, digest(configStr, archive))
+	manifest.AddKeys(kv.FilesNamespace, kvKeys...)
+	for key := range sris {
+		manifest.AddKeys(kv.SRIsNamespace, key)
 	}
-
-	if err := updateAggregatedMetadata(ctx, cfapi, pkg, version, newFiles); err != nil {
-		return fmt.Errorf("failed to update aggregated metadata: %s", err)
+	manifest.AddKeys(kv.VersionsNamespace, path.Join(pkgName, version))
+	if len(sris) > 0 {
+		manifest.AddKeys(kv.VersionMetadataNamespace, path.Join(pkgName, version, kv.SRIManifestName))
+	}
+	manifest.AddKeys(kv.AggregatedMetadataNamespace, pkgName)
+	manifest.AddKeys(kv.PackagesNamespace, pkgName)
+
+	steps := []kv.PublishStep{
+		{Name: filesStep, Apply: func(ctx context.Context) error {
+			return writeFiles(ctx, store, pkgName, version, pairs)
+		}},
+		{Name: srisStep, Apply: func(ctx context.Context) error {
+			if err := updateSRIs(ctx, store, sris); err != nil {
+				return errors.Wrap(err, "failed to update SRIs")
+			}
+			return updateSRIManifest(ctx, store, pkgName, version, sris)
+		}},
+		{Name: versionStep, Apply: func(ctx context.Context) error {
+			return updateVersions(ctx, store, pkg, version, newFiles)
+		}},
+		{Name: aggregatedStep, Apply: func(ctx context.Context) error {
+			return updateAggregatedMetadata(ctx, store, pkg, version, newFiles)
+		}},
+		{Name: packageStep, Apply: func(ctx context.Context) error {
+			return updatePackage(ctx, store, pkg, version, newFiles)
+		}},
 	}
 
-	if err := updatePackage(ctx, cfapi, pkg, version, newFiles); err != nil {
-		return fmt.Errorf("failed to update package: %s", err)
+	if _, err := kv.Publish(ctx, store, manifest, steps); err != nil {
+		return nil, nil, fmt.Errorf("failed to publish: %s", err)
 	}
 
-	if err := updateSRIs(ctx, cfapi, sris); err != nil {
-		return fmt.Errorf("failed to update SRIs: %s", err)
+	return sris, kvKeys, nil
+}
+
+// Writes the files of a version, except those whose content did not
+// change since the version was last published.
+func writeFiles(ctx context.Context, store kv.Store, pkgName, version string, pairs []kv.WriteRequest) error {
+	existing, err := kv.GetFilesMetadata(ctx, store, pkgName, version)
+	if err != nil {
+		return errors.Wrap(err, "failed to list existing files")
 	}
 
-	if err := audit.WroteKV(ctx, pkgName, version, sris, kvKeys, string(configStr)); err != nil {
-		log.Printf("failed to audit: %s\n", err)
+	changed := make([]kv.WriteRequest, 0, len(pairs))
+	for _, pair := range pairs {
+		if prev := existing[pair.GetKey()]; prev != nil && prev.ETag == pair.GetMeta().ETag && prev.Raw == pair.GetMeta().Raw {
+			continue
+		}
+		changed = append(changed, pair)
 	}
 
+	if unchanged := len(pairs) - len(changed); unchanged > 0 {
+		log.Printf("%s: %d files are unchanged\n", pkgName, unchanged)
+	}
+	if len(changed) > 0 {
+		if _, err := kv.EncodeAndWriteKVBulk(ctx, store, changed, kv.FilesNamespace, false); err != nil {
+			return errors.Wrap(err, "failed to write KV")
+		}
+	} else if len(pairs) == 0 {
+		log.Printf("%s: no files to publish\n", pkgName)
+	}
 	return nil
 }
 
-// KV has optimized files (ending in .gz/br), if we want the original files we
-// need to dedup them and remove their compression ext
-func cleanNewKVFiles(files []string) []string {
+// Hashes the config and the archive of a version, a publication of the
+// same content can be resumed.
+func digest(configStr []byte, archive []byte) string {
+	h := sha256.New()
+	h.Write(configStr)
+	h.Write(archive)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// KV has optimized files (ending in .br/.gz/.zst) and raw files, if we want
+// the original files we need to dedup them and remove their compression ext.
+// Raw files keep their name, even if it ends in a compression ext.
+func cleanNewKVFiles(files []string, raw map[string]bool) []string {
 	seen := make(map[string]bool)
 	out := make([]string, 0)
 	for _, file := range files {
-		ext := filepath.Ext(file)
-		name := file
-		if ext == ".gz" || ext == ".br" {
-			// remove trailing .gz or .br
-			name = file[0 : len(file)-len(ext)]
-		}
+		name, _ := compress.OriginalName(file, raw)
 
 		if _, ok := seen[name]; ok {
 			continue
@@ -157,18 +266,9 @@ func cleanNewKVFiles(files []string) []string {
 	return out
 }
 
-func getExistingVersions(cfapi *cloudflare.API, p *packages.Package) ([]string, error) {
-	versions, err := kv.GetVersions(cfapi, *p.Name)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get verions")
-	}
-
-	return versions, nil
-}
-
-func updateVersions(ctx context.Context, cfapi *cloudflare.API, pkg *packages.Package,
+func updateVersions(ctx context.Context, store kv.Store, pkg *packages.Package,
 	version string, files []string) error {
-	_, err := kv.UpdateKVVersion(ctx, cfapi, *pkg.Name, version, files)
+	_, err := kv.UpdateKVVersion(ctx, store, *pkg.Name, version, files)
 	if err != nil {
 		return errors.Wrap(err, "failed to update version in KV")
 	}
@@ -176,29 +276,41 @@ func updateVersions(ctx context.Context, cfapi *cloudflare.API, pkg *packages.Pa
 	return nil
 }
 
-func updatePackage(ctx context.Context, cfapi *cloudflare.API, pkg *packages.Package,
+func updatePackage(ctx context.Context, store kv.Store, pkg *packages.Package,
 	currVersion string, files []string) error {
 	// update package version with latest
-	versions, err := getExistingVersions(cfapi, pkg)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve existing versions: %s", err)
-	}
+	withFiles := []string{}
 	if len(files) > 0 {
 		// add the current version in case it was yet present in KV
-		versions = append(versions, currVersion)
+		withFiles = append(withFiles, currVersion)
 	} else {
 		log.Println("updatePackage: update contains no files, ignoring")
 	}
 
-	pkg.Version = packages.GetLatestStableVersion(versions)
+	latest, err := kv.GetLatestVersion(ctx, store, *pkg.Name, withFiles...)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve latest version: %s", err)
+	}
+	pkg.Version = latest
 	log.Println("updated package", pkg)
 
 	if err := packages.UpdateFilenameIfMissing(ctx, pkg, files); err != nil {
 		return errors.Wrap(err, "failed to fix missing filename")
 	}
 
+	// the human config does not record renames, keep them from the existing entry
+	existing, err := kv.GetPackage(ctx, store, *pkg.Name)
+	if err != nil {
+		if _, ok := err.(kv.KeyNotFoundError); !ok {
+			return errors.Wrap(err, "failed to get KV package metadata")
+		}
+	} else {
+		pkg.RenamedTo = existing.RenamedTo
+		pkg.FormerNames = existing.FormerNames
+	}
+
 	// sync with KV first, then update legacy package.json
-	if err := kv.UpdateKVPackage(ctx, cfapi, pkg); err != nil {
+	if err := kv.UpdateKVPackage(ctx, store, pkg); err != nil {
 		return errors.Wrap(err, "failed to write KV package metadata")
 	}
 	log.Println("updated package")
@@ -206,11 +318,11 @@ func updatePackage(ctx context.Context, cfapi *cloudflare.API, pkg *packages.Pac
 	return nil
 }
 
-func updateAggregatedMetadata(ctx context.Context, cfapi *cloudflare.API,
+func updateAggregatedMetadata(ctx context.Context, store kv.Store,
 	pkg *packages.Package, version string, newFiles []string) error {
 	if len(newFiles) == 0 {
 		log.Println("updateAggregatedMetadata: update contains no files")
-		kvWrites, wroteKV, err := kv.RemoveVersionFromAggregatedMetadata(cfapi, ctx, pkg, version)
+		kvWrites, wroteKV, err := kv.RemoveVersionFromAggregatedMetadata(ctx, store, pkg, version)
 		if err != nil {
 			return errors.Errorf("(%s) failed to update aggregated metadata (remove version %s): %s", *pkg.Name, version, err)
 		}
@@ -225,7 +337,7 @@ func updateAggregatedMetadata(ctx context.Context, cfapi *cloudflare.API,
 		Version: version,
 		Files:   newFiles,
 	}
-	kvWrites, _, err := kv.UpdateAggregatedMetadata(cfapi, ctx, pkg, version, newAssets)
+	kvWrites, _, err := kv.UpdateAggregatedMetadata(ctx, store, pkg, version, newAssets)
 	if err != nil {
 		return errors.Errorf("(%s) failed to update aggregated metadata: %s", *pkg.Name, err)
 	}
@@ -236,7 +348,7 @@ func updateAggregatedMetadata(ctx context.Context, cfapi *cloudflare.API,
 	return nil
 }
 
-func updateSRIs(ctx context.Context, cfapi *cloudflare.API, sris map[string]string) error {
+func updateSRIs(ctx context.Context, store kv.Store, sris map[string]string) error {
 	pairs := make([]kv.WriteRequest, 0)
 
 	for name, sri := range sris {
@@ -250,7 +362,7 @@ func updateSRIs(ctx context.Context, cfapi *cloudflare.API, sris map[string]stri
 	}
 
 	if len(pairs) > 0 {
-		_, err := kv.EncodeAndWriteKVBulk(ctx, cfapi, pairs, SRI_KV_NAMESPACE, false)
+		_, err := kv.EncodeAndWriteKVBulk(ctx, store, pairs, kv.SRIsNamespace, false)
 		if err != nil {
 			return errors.Wrap(err, "could not write bulk KV")
 		}
@@ -258,11 +370,30 @@ func updateSRIs(ctx context.Context, cfapi *cloudflare.API, sris map[string]stri
 	return nil
 }
 
-func newMetadata(size int) *kv.FileMetadata {
-	lastModifiedTime := time.Now()
-	lastModifiedSeconds := lastModifiedTime.UnixNano() / int64(time.Second)
-	lastModifiedStr := lastModifiedTime.Format(http.TimeFormat)
-	etag := fmt.Sprintf("%x-%x", lastModifiedSeconds, size)
+// Writes the SRIs of the version's files in a single manifest, by file name.
+func updateSRIManifest(ctx context.Context, store kv.Store, pkgName, version string, sris map[string]string) error {
+	if len(sris) == 0 {
+		return nil
+	}
+
+	prefix := fmt.Sprintf("%s/%s/", pkgName, version)
+	manifest := make(map[string]string, len(sris))
+	for key, sri := range sris {
+		manifest[strings.TrimPrefix(key, prefix)] = sri
+	}
+
+	if _, err := kv.UpdateKVSRIManifest(ctx, store, pkgName, version, manifest); err != nil {
+		return errors.Wrap(err, "could not write SRI manifest")
+	}
+	log.Printf("%s: wrote SRI manifest of %s (%d files)\n", pkgName, version, len(manifest))
+	return nil
+}
+
+// Creates the metadata of a file, its ETag is a hash of its content so that
+// publishing the same content again does not change it.
+func newMetadata(content []byte) *kv.FileMetadata {
+	lastModifiedStr := time.Now().Format(http.TimeFormat)
+	etag := fmt.Sprintf("%x", sha256.Sum256(content))
 
 	return &kv.FileMetadata{
 		ETag:         etag,
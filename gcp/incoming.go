@@ -1,7 +1,6 @@
 package gcp
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -19,9 +18,13 @@ var (
 	GCS_BUCKET = os.Getenv("GCS_BUCKET")
 )
 
-func AddIncomingFile(fileName string, buff bytes.Buffer, pckg *packages.Package, v version.Version) error {
+// AddIncomingFile streams a version archive to the incoming bucket.
+// If reading fails (ex. the archive is too big or does not match its
+// integrity), the upload is aborted and no object is created.
+func AddIncomingFile(fileName string, r io.Reader, pckg *packages.Package, v version.Version) error {
 	// Create GCS connection
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	client, err := storage.NewClient(ctx)
 	if err != nil {
 		return fmt.Errorf("HTTP response error: %v", err)
@@ -34,8 +37,11 @@ func AddIncomingFile(fileName string, buff bytes.Buffer, pckg *packages.Package,
 		{Entity: storage.AllUsers, Role: storage.RoleReader},
 	}
 
-	if _, err := io.Copy(w, bytes.NewReader(buff.Bytes())); err != nil {
-		return fmt.Errorf("Failed to copy to bucket: %v", err)
+	if _, err := io.Copy(w, r); err != nil {
+		// cancelling the context before closing the writer aborts the upload
+		cancel()
+		w.Close()
+		return errors.Wrap(err, "failed to copy to bucket")
 	}
 	if err := w.Close(); err != nil {
 		return fmt.Errorf("Failed to close: %v", err)
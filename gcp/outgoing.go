@@ -0,0 +1,68 @@
+package gcp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	b64 "encoding/base64"
+	"fmt"
+
+	"github.com/cdnjs/tools/packages"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+)
+
+// TakedownMetadata is the metadata of the outgoing objects that take
+// a version down, its value is the reason of the takedown.
+const TakedownMetadata = "takedown"
+
+// GetTakedown returns the reason of the takedown if the event is a
+// takedown of a version, or false for a processed version.
+func (e GCSEvent) GetTakedown() (string, bool) {
+	reason, ok := e.Metadata[TakedownMetadata].(string)
+	return reason, ok && reason != ""
+}
+
+// AddTakedown replaces the processed files of a version in the outgoing
+// bucket with an empty archive marked as a takedown. The functions and
+// git-sync consuming the bucket remove the version instead of publishing it.
+func AddTakedown(ctx context.Context, bucket string, pckg *packages.Package, version, reason string) error {
+	if reason == "" {
+		return errors.New("a takedown requires a reason")
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not create client")
+	}
+
+	configBytes, err := pckg.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal config")
+	}
+
+	name := fmt.Sprintf("%s/%s/files.tgz", *pckg.Name, version)
+	w := client.Bucket(bucket).Object(name).NewWriter(ctx)
+	w.Metadata = map[string]string{
+		"package":        *pckg.Name,
+		"version":        version,
+		"config":         b64.StdEncoding.EncodeToString(configBytes),
+		TakedownMetadata: reason,
+	}
+
+	// an empty archive, for consumers unaware of takedowns
+	zw := gzip.NewWriter(w)
+	if err := tar.NewWriter(zw).Close(); err != nil {
+		w.Close()
+		return errors.Wrap(err, "failed to write archive")
+	}
+	if err := zw.Close(); err != nil {
+		w.Close()
+		return errors.Wrap(err, "failed to write archive")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "failed to write takedown")
+	}
+	return nil
+}
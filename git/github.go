@@ -25,8 +25,19 @@ import (
 
 var (
 	GH_TOKEN = os.Getenv("GH_TOKEN")
+
+	// GITHUB_API_URL is the base URL of the GitHub API, overridable to
+	// point at a stand-in server.
+	GITHUB_API_URL = getEnvOr("GITHUB_API_URL", "https://api.github.com")
 )
 
+func getEnvOr(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return strings.TrimSuffix(v, "/")
+	}
+	return fallback
+}
+
 // Stars holds the number of stars for a GitHub repository.
 type Stars struct {
 	Stars uint `json:"stargazers_count"`
@@ -45,7 +56,7 @@ func getRepo(gitURL string) string {
 // particular GitHub repository.
 func GetGitHubStars(gitURL string) Stars {
 	gitHubRepository := getRepo(gitURL)
-	resp, err := http.Get(util.GetProtocol() + "://api.github.com/repos/" + gitHubRepository)
+	resp, err := http.Get(GITHUB_API_URL + "/repos/" + gitHubRepository)
 	util.Check(err)
 
 	defer resp.Body.Close()
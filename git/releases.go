@@ -0,0 +1,160 @@
+package git
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/cdnjs/tools/packages"
+	"github.com/cdnjs/tools/util"
+	"github.com/cdnjs/tools/version"
+
+	"github.com/blang/semver"
+	githubapi "github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// GetReleaseVersions gets the versions associated with the GitHub releases
+// of a repo, only keeping the release assets matching the package's fileMap.
+func GetReleaseVersions(ctx context.Context, config *packages.Autoupdate) ([]version.Version, error) {
+	return GetReleaseVersionsWithLimit(ctx, config, 10)
+}
+
+// GetReleaseVersionsWithLimit gets at most `limit` of the most recent
+// GitHub release versions of a repo.
+func GetReleaseVersionsWithLimit(ctx context.Context, config *packages.Autoupdate, limit int) ([]version.Version, error) {
+	return GetReleaseVersionsWithClient(ctx, GetClient(), config, limit)
+}
+
+// GetReleaseVersionsWithClient gets at most `limit` of the most recent
+// GitHub release versions of a repo, using a GitHub API client.
+func GetReleaseVersionsWithClient(ctx context.Context, client *githubapi.Client,
+	config *packages.Autoupdate, limit int) ([]version.Version, error) {
+	name := *config.Target
+	repo := getRepo(*config.Target)
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid GitHub repository: %s", name)
+	}
+
+	matchers, err := compileFileMap(config.FileMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid fileMap")
+	}
+
+	opts := &githubapi.ListOptions{PerPage: 100}
+
+	versions := make([]version.Version, 0)
+	for len(versions) < limit {
+		releases, resp, err := client.Repositories.ListReleases(ctx, parts[0], parts[1], opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list releases")
+		}
+
+		for _, release := range releases {
+			if len(versions) == limit {
+				break
+			}
+			if v := releaseVersion(name, config, release, matchers); v != nil {
+				versions = append(versions, *v)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return versions, nil
+}
+
+// Converts a GitHub release to a version, returning nil if the release
+// must be ignored.
+func releaseVersion(name string, config *packages.Autoupdate,
+	release *githubapi.RepositoryRelease, matchers []*util.Glob) *version.Version {
+	tag := release.GetTagName()
+
+	// drafts are only visible to the repository collaborators
+	if release.GetDraft() {
+		log.Printf("%s: release %s is a draft, ignoring\n", name, tag)
+		return nil
+	}
+
+	if version.IsVersionIgnored(config, tag) {
+		log.Printf("%s: version %s is ignored\n", name, tag)
+		return nil
+	}
+
+	// prereleases are published like the prerelease versions of npm, they
+	// never become the latest version as long as their tag is a semver
+	// prerelease. Otherwise (ex. `v2.0.0` marked as prerelease) the release
+	// would become the latest version, it is ignored.
+	if release.GetPrerelease() && !isSemverPrerelease(tag) {
+		log.Printf("%s: release %s is a prerelease without prerelease version, ignoring\n", name, tag)
+		return nil
+	}
+
+	assets := make([]version.Asset, 0)
+	for _, asset := range release.Assets {
+		if matchesAny(matchers, asset.GetName()) {
+			assets = append(assets, version.Asset{
+				Name: asset.GetName(),
+				URL:  asset.GetBrowserDownloadURL(),
+			})
+		}
+	}
+
+	if len(assets) == 0 {
+		log.Printf("%s: release %s has no matching assets, ignoring\n", name, tag)
+		return nil
+	}
+
+	versionName := tag
+	if versionName[0:1] == "v" {
+		versionName = versionName[1:]
+	}
+
+	return &version.Version{
+		Version: versionName,
+		Tarball: release.GetTarballURL(),
+		Date:    release.GetPublishedAt().Time,
+		Source:  "github-release",
+		Assets:  assets,
+	}
+}
+
+// Returns if a tag is a semver prerelease version (ex. `v2.0.0-beta.1`).
+func isSemverPrerelease(tag string) bool {
+	v, err := semver.Parse(strings.TrimPrefix(tag, "v"))
+	return err == nil && len(v.Pre) > 0
+}
+
+// Compiles the globs of a fileMap, with the same semantics as the fileMaps
+// of the other sources. The release assets are at the root of the processed
+// version, so base paths are not supported.
+func compileFileMap(fileMap []packages.FileMap) ([]*util.Glob, error) {
+	var matchers []*util.Glob
+	for _, m := range fileMap {
+		if m.BasePath != nil && *m.BasePath != "" {
+			return nil, errors.Errorf("basePath `%s` is not supported for GitHub releases", *m.BasePath)
+		}
+		for _, pattern := range m.Files {
+			g, err := util.CompileGlob(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid pattern `%s`", pattern)
+			}
+			matchers = append(matchers, g)
+		}
+	}
+	return matchers, nil
+}
+
+func matchesAny(matchers []*util.Glob, name string) bool {
+	for _, g := range matchers {
+		if g.Match(name) {
+			return true
+		}
+	}
+	return false
+}
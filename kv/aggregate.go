@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
+	"path"
+	"time"
 
 	"github.com/cdnjs/tools/compress"
 	"github.com/cdnjs/tools/packages"
 	"github.com/cdnjs/tools/util"
 
-	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
 )
 
 // GetVersionsFromAggregatedMetadata gets the list version for a particular package
@@ -18,8 +21,8 @@ import (
 //
 // The aggregated metadata will only contain non-empty versions, so this is useful
 // for updating Algolia.
-func GetVersionsFromAggregatedMetadata(api *cloudflare.API, pckgname string) ([]string, error) {
-	aggPkg, err := getAggregatedMetadata(api, pckgname)
+func GetVersionsFromAggregatedMetadata(ctx context.Context, store Store, pckgname string) ([]string, error) {
+	aggPkg, err := getAggregatedMetadata(ctx, store, pckgname)
 	if err != nil {
 		log.Printf("Fetching aggregated metadata for `%s`: %s\n", pckgname, err.Error())
 		switch err.(type) {
@@ -43,85 +46,183 @@ func GetVersionsFromAggregatedMetadata(api *cloudflare.API, pckgname string) ([]
 	return versions, nil
 }
 
+// Number of attempts to update the aggregated metadata of a package. Each
+// conflict means that a concurrent update succeeded, so that as many
+// concurrent updates of a package are all applied.
+const aggregatedMetadataAttempts = 20
+
 // RemoveVersionFromAggregatedMetadata will remove a particular version from
 // a package's KV entry for aggregated metadata if it exists.
 // This is useful for removing empty versions with no files.
-func RemoveVersionFromAggregatedMetadata(api *cloudflare.API, ctx context.Context, pkg *packages.Package, version string) ([]string, bool, error) {
-	aggPkg, err := getAggregatedMetadata(api, *pkg.Name)
-	if err != nil {
-		switch err.(type) {
-		case KeyNotFoundError:
-			{
-				// key not found
-				log.Printf("Removing version %s from aggregated metadata: KV key `%s` not found, ignoring\n", version, *pkg.Name)
-				return nil, false, nil
-			}
-		default:
-			{
-				// api error
-				return nil, false, err
-			}
+func RemoveVersionFromAggregatedMetadata(ctx context.Context, store Store, pkg *packages.Package, version string) ([]string, bool, error) {
+	var wroteKV bool
+	successfulWrites, err := modifyAggregatedMetadata(ctx, store, *pkg.Name, func(aggPkg *packages.Package) (*packages.Package, error) {
+		wroteKV = false
+		if aggPkg == nil {
+			// key not found
+			log.Printf("Removing version %s from aggregated metadata: KV key `%s` not found, ignoring\n", version, *pkg.Name)
+			return nil, nil
 		}
-	}
 
-	if !aggPkg.HasVersion(version) {
-		log.Printf("Removing version %s from aggregated metadata: version does not exist\n", version)
-		return nil, false, nil
-	}
+		if !aggPkg.HasVersion(version) {
+			log.Printf("Removing version %s from aggregated metadata: version does not exist\n", version)
+			return nil, nil
+		}
+
+		// remove the version
+		log.Printf("Removing version %s from aggregated metadata: version found\n", version)
+		aggPkg.RemoveVersion(version)
 
-	// remove the version
-	log.Printf("Removing version %s from aggregated metadata: version found\n", version)
-	aggPkg.RemoveVersion(version)
+		// the removed version can no longer be the current one
+		if aggPkg.Version != nil && *aggPkg.Version == version {
+			versions := make([]string, 0, len(aggPkg.Assets))
+			for _, asset := range aggPkg.Assets {
+				versions = append(versions, asset.Version)
+			}
+			aggPkg.Version = packages.GetLatestStableVersion(versions)
+		}
 
-	successfulWrites, err := writeAggregatedMetadata(ctx, api, aggPkg)
-	return successfulWrites, true, err
+		wroteKV = true
+		return aggPkg, nil
+	})
+	return successfulWrites, wroteKV, err
 }
 
 // UpdateAggregatedMetadata updates a package's KV entry for aggregated metadata.
 // Returns the keys written to KV, whether the existing entry was found, and if there were any errors.
-func UpdateAggregatedMetadata(api *cloudflare.API, ctx context.Context,
+func UpdateAggregatedMetadata(ctx context.Context, store Store,
 	pkg *packages.Package, newVersion string, newAssets packages.Asset) ([]string, bool, error) {
-	aggPkg, err := getAggregatedMetadata(api, *pkg.Name)
-
-	if aggPkg == nil {
-		// pkg has never been aggregated
-		aggPkg = pkg
-	}
-
 	var found bool
-	if err != nil {
-		switch err.(type) {
-		case KeyNotFoundError:
-			{
-				// key not found (new package)
-				log.Printf("KV key `%s` not found, inserting aggregated metadata...\n", *pkg.Name)
-				aggPkg.Assets = []packages.Asset{newAssets}
-			}
-		default:
-			{
-				return nil, false, err
-			}
-		}
-	} else {
-		if !aggPkg.HasVersion(newVersion) {
+	successfulWrites, err := modifyAggregatedMetadata(ctx, store, *pkg.Name, func(aggPkg *packages.Package) (*packages.Package, error) {
+		found = aggPkg != nil
+		if aggPkg == nil {
+			// key not found (new package), pkg has never been aggregated
+			log.Printf("KV key `%s` not found, inserting aggregated metadata...\n", *pkg.Name)
+			aggPkg = pkg
+			aggPkg.Assets = []packages.Asset{newAssets}
+		} else if !aggPkg.HasVersion(newVersion) {
 			aggPkg.Assets = append(aggPkg.Assets, newAssets)
 			log.Printf("Aggregated metadata for `%s` found. Updating aggregated metadata...\n", *pkg.Name)
 		} else {
 			log.Printf("Aggregated metadata for `%s` found. Version already exists, updating\n", *pkg.Name)
 			aggPkg.UpdateVersion(newVersion, newAssets)
 		}
-		found = true
+		aggPkg.Version = &newVersion
+		return aggPkg, nil
+	})
+	return successfulWrites, found, err
+}
+
+// Reads the aggregated metadata of a package, nil if it does not exist, and
+// writes the package returned by modify, unless it is nil. If the store is a
+// ConditionalStore and the entry was updated concurrently in between, it is
+// read and modified again. Otherwise the entry is written with the versions
+// lost by a concurrent update restored from their version entries.
+func modifyAggregatedMetadata(ctx context.Context, store Store, name string,
+	modify func(aggPkg *packages.Package) (*packages.Package, error)) ([]string, error) {
+	_, conditional := store.(ConditionalStore)
+	for attempt := 1; ; attempt++ {
+		// read the revision first, a concurrent update in
+		// between is detected when writing
+		var revision int64
+		if conditional {
+			var err error
+			revision, err = readRevision(ctx, store, AggregatedMetadataNamespace, name)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read revision")
+			}
+		}
+
+		aggPkg, err := getAggregatedMetadata(ctx, store, name)
+		if err != nil {
+			if _, ok := err.(KeyNotFoundError); !ok {
+				return nil, err
+			}
+			aggPkg = nil
+		}
+
+		aggPkg, err = modify(aggPkg)
+		if err != nil || aggPkg == nil {
+			return nil, err
+		}
+
+		if !conditional {
+			return writeAggregatedMetadataWithVersions(ctx, store, name, aggPkg)
+		}
+
+		successfulWrites, err := writeAggregatedMetadata(ctx, store, aggPkg, revision)
+		if mismatch, ok := err.(RevisionMismatchError); ok && attempt < aggregatedMetadataAttempts {
+			log.Printf("%s, retrying (attempt %d)\n", mismatch, attempt)
+			time.Sleep(time.Duration(rand.Intn(10*attempt)) * time.Millisecond)
+			continue
+		}
+		return successfulWrites, err
 	}
-	aggPkg.Version = &newVersion
+}
 
-	successfulWrites, err := writeAggregatedMetadata(ctx, api, aggPkg)
-	return successfulWrites, found, err
+// Writes the aggregated metadata of a package to a store where the last
+// concurrent update wins. The versions with files missing from it are added
+// from their version entries, which are written before the aggregated
+// metadata, and listed again once written until none is missing: the last
+// update includes the versions of the concurrent ones. Workers KV lists
+// keys eventually, a version missed anyway is restored by AuditPackage.
+func writeAggregatedMetadataWithVersions(ctx context.Context, store Store, name string, aggPkg *packages.Package) ([]string, error) {
+	var successfulWrites []string
+	for attempt := 1; attempt <= aggregatedMetadataAttempts; attempt++ {
+		added, err := addMissingVersions(ctx, store, name, aggPkg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to add missing versions")
+		}
+		if attempt > 1 {
+			if added == 0 {
+				break
+			}
+			log.Printf("%s: added %d versions lost by a concurrent update (attempt %d)\n", name, added, attempt)
+		}
+
+		successfulWrites, err = writeAggregatedMetadata(ctx, store, aggPkg, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return successfulWrites, nil
+}
+
+// Adds the versions of a package with files missing from its aggregated
+// metadata, from their version entries. Returns the number of added versions.
+func addMissingVersions(ctx context.Context, store Store, name string, aggPkg *packages.Package) (int, error) {
+	versions, err := GetVersions(ctx, store, name)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get versions")
+	}
+
+	added := 0
+	for _, version := range versions {
+		if aggPkg.HasVersion(version) {
+			continue
+		}
+		files, err := GetVersion(ctx, store, path.Join(name, version))
+		if err != nil {
+			if _, ok := err.(KeyNotFoundError); ok {
+				// deleted version still listed
+				continue
+			}
+			return 0, errors.Wrapf(err, "failed to get version %s", version)
+		}
+		// removed from the aggregated metadata
+		if len(files) == 0 {
+			continue
+		}
+		aggPkg.Assets = append(aggPkg.Assets, packages.Asset{Version: version, Files: files})
+		added++
+	}
+	return added, nil
 }
 
 // Reads an aggregated metadata entry in KV, ungzipping it and
 // unmarshalling it into a *packages.Package.
-func getAggregatedMetadata(api *cloudflare.API, key string) (*packages.Package, error) {
-	gzipBytes, err := read(api, key, aggregatedMetadataNamespaceID)
+func getAggregatedMetadata(ctx context.Context, store Store, key string) (*packages.Package, error) {
+	gzipBytes, err := store.Read(ctx, AggregatedMetadataNamespace, key)
 
 	if err != nil {
 		return nil, err
@@ -134,8 +235,9 @@ func getAggregatedMetadata(api *cloudflare.API, key string) (*packages.Package,
 	return &p, nil
 }
 
-// Writes an aggregated metadata entry to KV, gzipping the bytes.
-func writeAggregatedMetadata(ctx context.Context, api *cloudflare.API, p *packages.Package) ([]string, error) {
+// Writes an aggregated metadata entry to KV, gzipping the bytes, if its
+// revision is still the one it was read at.
+func writeAggregatedMetadata(ctx context.Context, store Store, p *packages.Package, revision int64) ([]string, error) {
 	// marshal package into JSON
 	v, err := p.Marshal()
 	if err != nil {
@@ -143,12 +245,17 @@ func writeAggregatedMetadata(ctx context.Context, api *cloudflare.API, p *packag
 	}
 
 	// gzip the bytes
-	req := &ConsumableWriteRequest{
-		Name:  *p.Name,
+	pair := &Pair{
 		Key:   *p.Name,
 		Value: compress.Gzip9Bytes(v),
 	}
+	if size := int64(len(pair.Value)); size > util.MaxFileSize {
+		panic(fmt.Sprintf("oversized file: %s (%d)", pair.Key, size))
+	}
 
 	// write aggregated to KV
-	return EncodeAndWriteKVBulk(ctx, api, []WriteRequest{req}, aggregatedMetadataNamespaceID, true)
+	if err := writeIfRevision(ctx, store, AggregatedMetadataNamespace, pair, revision); err != nil {
+		return nil, err
+	}
+	return []string{*p.Name}, nil
 }
@@ -6,6 +6,8 @@ type FileMetadata struct {
 	ETag         string `json:"etag,omitempty"`
 	LastModified string `json:"last_modified,omitempty"`
 	SRI          string `json:"sri,omitempty"`
+	Revision     int64  `json:"revision,omitempty"` // incremented on each update of entries written with WriteIfRevision
+	Raw          bool   `json:"raw,omitempty"`      // the file is stored as-is, not a compressed variant
 }
 
 // Represents a KV write request, consisting of
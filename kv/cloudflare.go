@@ -0,0 +1,228 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cdnjs/tools/util"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+)
+
+// CloudflareStore is a Store backed by Workers KV.
+type CloudflareStore struct {
+	api        *cloudflare.API
+	namespaces NamespaceIDs
+}
+
+// NewCloudflareStore creates a Store backed by Workers KV, using
+// the namespace IDs to map each Namespace to a Workers KV namespace.
+func NewCloudflareStore(api *cloudflare.API, namespaces NamespaceIDs) *CloudflareStore {
+	return &CloudflareStore{api, namespaces}
+}
+
+// NewCloudflareStoreFromEnv creates a Store backed by Workers KV using
+// an API token, an account ID and namespace IDs from the environment.
+func NewCloudflareStoreFromEnv(token, accountID string) (*CloudflareStore, error) {
+	api, err := cloudflare.NewWithAPIToken(token, cloudflare.UsingAccount(accountID))
+	if err != nil {
+		return nil, err
+	}
+	return NewCloudflareStore(api, NamespaceIDsFromEnv()), nil
+}
+
+// Gets the Workers KV namespace ID of a Namespace.
+func (s *CloudflareStore) namespaceID(ns Namespace) (string, error) {
+	if id, ok := s.namespaces[ns]; ok && id != "" {
+		return id, nil
+	}
+	return "", fmt.Errorf("no namespace ID configured for `%s`", ns)
+}
+
+// Ensure a response is successful and the error is nil.
+func checkSuccess(r cloudflare.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if !r.Success {
+		return fmt.Errorf("kv fail: %v", r)
+	}
+	return nil
+}
+
+// Read reads an entry from Workers KV.
+func (s *CloudflareStore) Read(ctx context.Context, ns Namespace, key string) ([]byte, error) {
+	namespaceID, err := s.namespaceID(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	var bytes []byte
+	for i := 0; i < util.MaxKVAttempts; i++ {
+		bytes, err = s.api.ReadWorkersKV(ctx, namespaceID, key)
+		if err != nil {
+			errString := err.Error()
+
+			// check for service failure and retry
+			if strings.Contains(errString, serviceFailure) {
+				continue
+			}
+
+			// check for key not found
+			if strings.Contains(errString, keyNotFound) {
+				return nil, KeyNotFoundError{key, errString}
+			}
+
+			// check for authentication error
+			if strings.Contains(errString, authError) {
+				return nil, AuthError{errString}
+			}
+		}
+
+		break
+	}
+
+	return bytes, err
+}
+
+// Encodes a byte array to a base64 string.
+func encodeToBase64(bytes []byte) string {
+	return base64.StdEncoding.EncodeToString(bytes)
+}
+
+// WriteBulk encodes pairs to base64 and writes them to Workers KV
+// in multiple bulk requests.
+func (s *CloudflareStore) WriteBulk(ctx context.Context, ns Namespace, pairs []*Pair) error {
+	namespaceID, err := s.namespaceID(ns)
+	if err != nil {
+		return err
+	}
+
+	var bulkWrites []cloudflare.WorkersKVBulkWriteRequest
+	var bulkWrite []*cloudflare.WorkersKVPair
+	var totalSize, totalKeys int64
+
+	for _, p := range pairs {
+		// Note that after encoding in base64 the size may get larger, but after decoding
+		// it will be reduced, so it is okay if the size is larger than util.MaxFileSize after encoding base64.
+		// However, we still need to check for the KV bulk request limit of 100MiB.
+		encodedValue := encodeToBase64(p.Value)
+		size := int64(len(encodedValue))
+		writePair := &cloudflare.WorkersKVPair{
+			Key:    p.Key,
+			Value:  encodedValue,
+			Base64: true,
+		}
+		if p.Metadata != nil {
+			bytes, err := json.Marshal(p.Metadata)
+			if err != nil {
+				return err
+			}
+			writePair.Metadata = p.Metadata
+			size += int64(len(bytes))
+		}
+		if totalSize+size > util.MaxBulkWritePayload || totalKeys == util.MaxBulkKeys {
+			// Create a new bulk since we are over a limit.
+			bulkWrites = append(bulkWrites, bulkWrite)
+			bulkWrite = []*cloudflare.WorkersKVPair{}
+			totalSize = 0
+			totalKeys = 0
+		}
+		bulkWrite = append(bulkWrite, writePair)
+		totalSize += size
+		totalKeys++
+	}
+	bulkWrites = append(bulkWrites, bulkWrite)
+
+	for i, b := range bulkWrites {
+		log.Printf("writing bulk %d/%d (keys=%d)...\n", i+1, len(bulkWrites), len(b))
+		for j := 0; j < util.MaxKVAttempts; j++ {
+			r, err := s.api.WriteWorkersKVBulk(ctx, namespaceID, b)
+
+			// check for service failure and retry
+			if err != nil && strings.Contains(err.Error(), serviceFailure) {
+				if j == util.MaxKVAttempts-1 {
+					return err // no more attempts
+				}
+				continue // retry
+			}
+
+			if err = checkSuccess(r, err); err != nil {
+				return err
+			}
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// List lists a page of keys from Workers KV.
+func (s *CloudflareStore) List(ctx context.Context, ns Namespace, prefix, cursor string) (*ListResult, error) {
+	namespaceID, err := s.namespaceID(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	o := cloudflare.ListWorkersKVsOptions{
+		Prefix: &prefix,
+	}
+	if cursor != "" {
+		o.Cursor = &cursor
+	}
+
+	resp, err := s.api.ListWorkersKVsWithOptions(ctx, namespaceID, o)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListResult{Cursor: resp.Cursor}
+	for _, k := range resp.Result {
+		meta, err := decodeMetadata(k.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata for `%s`: %s", k.Name, err)
+		}
+		res.Keys = append(res.Keys, Key{Name: k.Name, Metadata: meta})
+	}
+	return res, nil
+}
+
+// Delete deletes keys from Workers KV in multiple bulk requests.
+func (s *CloudflareStore) Delete(ctx context.Context, ns Namespace, keys []string) error {
+	namespaceID, err := s.namespaceID(ns)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(keys); start += int(util.MaxBulkKeys) {
+		end := start + int(util.MaxBulkKeys)
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := checkSuccess(s.api.DeleteWorkersKVBulk(ctx, namespaceID, keys[start:end])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decodes the untyped metadata returned by the Workers KV API.
+func decodeMetadata(v interface{}) (*FileMetadata, error) {
+	if v == nil {
+		return nil, nil
+	}
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var meta FileMetadata
+	if err := json.Unmarshal(bytes, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
@@ -0,0 +1,276 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cdnjs/tools/compress"
+	"github.com/cdnjs/tools/packages"
+
+	"github.com/pkg/errors"
+)
+
+// The kinds of inconsistencies between the namespaces.
+const (
+	// a version with files is missing from the aggregated metadata
+	MissingAggregatedVersion = "missing-aggregated-version"
+	// a version of the aggregated metadata has no files or no version entry
+	StaleAggregatedVersion = "stale-aggregated-version"
+	// the version of the package entry is not its latest version
+	StalePackageVersion = "stale-package-version"
+	// a package has versions but no package entry
+	MissingPackage = "missing-package"
+	// a file listed in a version entry is not in the files namespace
+	MissingFile = "missing-file"
+	// a key of the files namespace is not listed in a version entry
+	OrphanedFile = "orphaned-file"
+	// a key of the SRIs namespace is not listed in a version entry
+	OrphanedSRI = "orphaned-sri"
+)
+
+// Inconsistency represents a drift between the namespaces of a package.
+type Inconsistency struct {
+	Kind     string
+	Package  string
+	Key      string // the version, or the key for files
+	Message  string
+	Repaired bool
+}
+
+// String represents the inconsistency as a human-readable text.
+func (i Inconsistency) String() string {
+	s := fmt.Sprintf("%s %s", i.Kind, i.Key)
+	if i.Message != "" {
+		s += ": " + i.Message
+	}
+	if i.Repaired {
+		s += " (repaired)"
+	}
+	return s
+}
+
+// ListPackages lists the names of all the packages, from the package
+// entries and the version entries, which may exist without the former.
+func ListPackages(ctx context.Context, store Store) ([]string, error) {
+	names := make(map[string]bool)
+
+	pkgs, err := listByPrefixNamesOnly(ctx, store, "", PackagesNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list packages")
+	}
+	for _, name := range pkgs {
+		names[name] = true
+	}
+
+	versions, err := listByPrefixNamesOnly(ctx, store, "", VersionsNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list versions")
+	}
+	for _, key := range versions {
+		names[strings.SplitN(key, "/", 2)[0]] = true
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// AuditPackage reports the inconsistencies between the version entries,
+// the aggregated metadata, the package entry and the files of a package.
+// If repair is set, the aggregated metadata and the package entry are
+// fixed from the version entries. Missing and orphaned files are only
+// reported, they may belong to a version being published.
+func AuditPackage(ctx context.Context, store Store, pkgName string, repair bool) ([]Inconsistency, error) {
+	report := make([]Inconsistency, 0)
+	add := func(kind, key, message string, repaired bool) {
+		report = append(report, Inconsistency{kind, pkgName, key, message, repaired})
+	}
+
+	versions, err := GetVersions(ctx, store, pkgName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get versions")
+	}
+
+	// the files of each version, the aggregated metadata only
+	// contains the versions with files
+	files := make(map[string][]string, len(versions))
+	nonEmpty := make([]string, 0, len(versions))
+	for _, version := range versions {
+		list, err := GetVersion(ctx, store, path.Join(pkgName, version))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get version %s", version)
+		}
+		files[version] = list
+		if len(list) > 0 {
+			nonEmpty = append(nonEmpty, version)
+		}
+	}
+	latest, err := latestVersion(versions, func(version string) (bool, error) {
+		return len(files[version]) > 0, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get latest version")
+	}
+
+	// package entry
+	pkg, err := GetPackage(ctx, store, pkgName)
+	if err != nil {
+		if _, ok := err.(KeyNotFoundError); !ok {
+			return nil, errors.Wrap(err, "failed to get package")
+		}
+		if len(versions) > 0 {
+			add(MissingPackage, pkgName, "", false)
+		}
+		pkg = nil
+	} else if !equalVersions(pkg.Version, latest) {
+		message := fmt.Sprintf("%s instead of %s", printVersion(pkg.Version), printVersion(latest))
+		repaired := false
+		if repair && latest != nil {
+			pkg.Version = latest
+			if err := UpdateKVPackage(ctx, store, pkg); err != nil {
+				return nil, errors.Wrap(err, "failed to repair package")
+			}
+			repaired = true
+		}
+		add(StalePackageVersion, pkgName, message, repaired)
+	}
+
+	// aggregated metadata
+	aggPkg, err := getAggregatedMetadata(ctx, store, pkgName)
+	if err != nil {
+		if _, ok := err.(KeyNotFoundError); !ok {
+			return nil, errors.Wrap(err, "failed to get aggregated metadata")
+		}
+		aggPkg = nil
+	}
+	missing, stale := diffAggregatedMetadata(aggPkg, nonEmpty, files)
+
+	repairAggregated := repair && (len(missing) > 0 || len(stale) > 0) && (aggPkg != nil || pkg != nil)
+	if repairAggregated {
+		_, err := modifyAggregatedMetadata(ctx, store, pkgName, func(aggPkg *packages.Package) (*packages.Package, error) {
+			if aggPkg == nil {
+				if pkg == nil {
+					return nil, nil
+				}
+				// pkg has never been aggregated
+				copied := *pkg
+				aggPkg = &copied
+				aggPkg.Assets = nil
+			}
+			// the entry may have been updated since it was audited
+			missing, stale := diffAggregatedMetadata(aggPkg, nonEmpty, files)
+			for _, version := range stale {
+				aggPkg.RemoveVersion(version)
+			}
+			aggPkg.Assets = append(aggPkg.Assets, missing...)
+			aggPkg.Version = latest
+			return aggPkg, nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to repair aggregated metadata")
+		}
+	}
+	for _, asset := range missing {
+		add(MissingAggregatedVersion, asset.Version, "", repairAggregated)
+	}
+	for _, version := range stale {
+		add(StaleAggregatedVersion, version, "", repairAggregated)
+	}
+
+	// files
+	listed := make(map[string]bool)
+	for version, list := range files {
+		for _, file := range list {
+			listed[path.Join(pkgName, version, file)] = true
+		}
+	}
+
+	keys, err := listByPrefix(ctx, store, pkgName+"/", FilesNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list files")
+	}
+	present := make(map[string]bool)
+	for _, key := range keys {
+		original := key.Name
+		if key.Metadata == nil || !key.Metadata.Raw {
+			original, _ = compress.TrimExt(key.Name)
+		}
+		present[original] = true
+		if !listed[original] {
+			add(OrphanedFile, key.Name, "", false)
+		}
+	}
+
+	missingFiles := make([]string, 0)
+	for key := range listed {
+		if !present[key] {
+			missingFiles = append(missingFiles, key)
+		}
+	}
+	sort.Strings(missingFiles)
+	for _, key := range missingFiles {
+		add(MissingFile, key, "", false)
+	}
+
+	sris, err := listByPrefixNamesOnly(ctx, store, pkgName+"/", SRIsNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list SRIs")
+	}
+	for _, key := range sris {
+		if !listed[key] {
+			add(OrphanedSRI, key, "", false)
+		}
+	}
+
+	if len(report) > 0 {
+		log.Printf("%s: %d inconsistencies\n", pkgName, len(report))
+	}
+	return report, nil
+}
+
+// Gets the versions with files missing from the aggregated metadata,
+// and the versions of the aggregated metadata without files.
+func diffAggregatedMetadata(aggPkg *packages.Package, nonEmpty []string, files map[string][]string) ([]packages.Asset, []string) {
+	aggregated := make(map[string]bool)
+	if aggPkg != nil {
+		for _, asset := range aggPkg.Assets {
+			aggregated[asset.Version] = true
+		}
+	}
+
+	missing := make([]packages.Asset, 0)
+	for _, version := range nonEmpty {
+		if !aggregated[version] {
+			missing = append(missing, packages.Asset{Version: version, Files: files[version]})
+		}
+	}
+	stale := make([]string, 0)
+	for version := range aggregated {
+		if len(files[version]) == 0 {
+			stale = append(stale, version)
+		}
+	}
+	sort.Strings(stale)
+	return missing, stale
+}
+
+func equalVersions(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func printVersion(v *string) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return *v
+}
@@ -0,0 +1,21 @@
+package kv
+
+import (
+	"context"
+	"path"
+)
+
+// GetFilesMetadata gets the metadata of the files of a version,
+// including their compressed variants, by key.
+func GetFilesMetadata(ctx context.Context, store Store, pkg, version string) (map[string]*FileMetadata, error) {
+	keys, err := listByPrefix(ctx, store, path.Join(pkg, version)+"/", FilesNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]*FileMetadata, len(keys))
+	for _, k := range keys {
+		metadata[k.Name] = k.Metadata
+	}
+	return metadata, nil
+}
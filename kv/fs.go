@@ -0,0 +1,174 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FSStore is a Store that keeps entries on disk, intended for local development.
+// Each namespace is a directory under the root, containing a `values` and a
+// `metadata` directory, where each key is stored as a single path-escaped file.
+type FSStore struct {
+	mu   sync.RWMutex
+	root string
+}
+
+// NewFSStore creates a Store on disk under a root directory.
+func NewFSStore(root string) (*FSStore, error) {
+	for _, ns := range Namespaces {
+		for _, dir := range []string{"values", "metadata"} {
+			if err := os.MkdirAll(path.Join(root, string(ns), dir), 0755); err != nil {
+				return nil, errors.Wrap(err, "could not create store directory")
+			}
+		}
+	}
+	return &FSStore{root: root}, nil
+}
+
+func (s *FSStore) valuePath(ns Namespace, key string) string {
+	return path.Join(s.root, string(ns), "values", url.PathEscape(key))
+}
+
+func (s *FSStore) metadataPath(ns Namespace, key string) string {
+	return path.Join(s.root, string(ns), "metadata", url.PathEscape(key))
+}
+
+// Read reads an entry from disk.
+func (s *FSStore) Read(ctx context.Context, ns Namespace, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bytes, err := ioutil.ReadFile(s.valuePath(ns, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, KeyNotFoundError{key, err.Error()}
+		}
+		return nil, errors.Wrap(err, "could not read value")
+	}
+	return bytes, nil
+}
+
+// WriteBulk writes a number of pairs to disk.
+func (s *FSStore) WriteBulk(ctx context.Context, ns Namespace, pairs []*Pair) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range pairs {
+		if err := s.write(ns, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteIfRevision writes a pair to disk if the revision
+// of the existing entry is the expected one.
+func (s *FSStore) WriteIfRevision(ctx context.Context, ns Namespace, pair *Pair, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, err := s.readMetadata(ns, pair.Key)
+	if err != nil {
+		return err
+	}
+	if actual := revisionOf(meta); actual != revision {
+		return RevisionMismatchError{pair.Key, revision, actual}
+	}
+	return s.write(ns, pair)
+}
+
+// Writes the value and the metadata of a pair, the lock must be held.
+func (s *FSStore) write(ns Namespace, p *Pair) error {
+	if err := ioutil.WriteFile(s.valuePath(ns, p.Key), p.Value, 0644); err != nil {
+		return errors.Wrapf(err, "could not write value for `%s`", p.Key)
+	}
+
+	metaPath := s.metadataPath(ns, p.Key)
+	if p.Metadata == nil {
+		if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "could not remove metadata for `%s`", p.Key)
+		}
+		return nil
+	}
+	bytes, err := json.Marshal(p.Metadata)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal metadata for `%s`", p.Key)
+	}
+	if err := ioutil.WriteFile(metaPath, bytes, 0644); err != nil {
+		return errors.Wrapf(err, "could not write metadata for `%s`", p.Key)
+	}
+	return nil
+}
+
+// List lists a page of keys from disk.
+func (s *FSStore) List(ctx context.Context, ns Namespace, prefix, cursor string) (*ListResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos, err := ioutil.ReadDir(path.Join(s.root, string(ns), "values"))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list values")
+	}
+
+	var names []string
+	for _, info := range infos {
+		name, err := url.PathUnescape(info.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key file `%s`", info.Name())
+		}
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+
+	page, next := paginate(names, cursor)
+
+	res := &ListResult{Cursor: next}
+	for _, name := range page {
+		meta, err := s.readMetadata(ns, name)
+		if err != nil {
+			return nil, err
+		}
+		res.Keys = append(res.Keys, Key{Name: name, Metadata: meta})
+	}
+	return res, nil
+}
+
+// Reads the metadata of a key, if any.
+func (s *FSStore) readMetadata(ns Namespace, key string) (*FileMetadata, error) {
+	bytes, err := ioutil.ReadFile(s.metadataPath(ns, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "could not read metadata for `%s`", key)
+	}
+	var meta FileMetadata
+	if err := json.Unmarshal(bytes, &meta); err != nil {
+		return nil, errors.Wrapf(err, "could not parse metadata for `%s`", key)
+	}
+	return &meta, nil
+}
+
+// Delete deletes keys from disk.
+func (s *FSStore) Delete(ctx context.Context, ns Namespace, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		for _, p := range []string{s.valuePath(ns, key), s.metadataPath(ns, key)} {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return errors.Wrapf(err, "could not delete `%s`", key)
+			}
+		}
+	}
+	return nil
+}
@@ -2,17 +2,12 @@ package kv
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	// "sort"
-	"strings"
 
 	"github.com/cdnjs/tools/sentry"
 	"github.com/cdnjs/tools/util"
-	cloudflare "github.com/cloudflare/cloudflare-go"
 )
 
 const (
@@ -23,12 +18,6 @@ const (
 	serviceFailure = "service failure"
 )
 
-var (
-	versionsNamespaceID           = os.Getenv("WORKERS_KV_VERSIONS_NAMESPACE_ID")
-	packagesNamespaceID           = os.Getenv("WORKERS_KV_PACKAGES_NAMESPACE_ID")
-	aggregatedMetadataNamespaceID = os.Getenv("WORKERS_KV_AGGREGATED_METADATA_NAMESPACE_ID")
-)
-
 // KeyNotFoundError represents a KV key not found.
 type KeyNotFoundError struct {
 	key string
@@ -50,61 +39,12 @@ func (a AuthError) Error() string {
 	return fmt.Sprintf("%s: %s", authError, a.err)
 }
 
-// Ensure a response is successful and the error is nil.
-func checkSuccess(r cloudflare.Response, err error) error {
-	if err != nil {
-		return err
-	}
-	if !r.Success {
-		return fmt.Errorf("kv fail: %v", r)
-	}
-	return nil
-}
-
-// read reads an entry from Workers KV.
-func read(api *cloudflare.API, key string, namespaceID string) ([]byte, error) {
-	var bytes []byte
-	var err error
-	for i := 0; i < util.MaxKVAttempts; i++ {
-		bytes, err = api.ReadWorkersKV(context.Background(), namespaceID, key)
-		if err != nil {
-			errString := err.Error()
-
-			// check for service failure and retry
-			if strings.Contains(errString, serviceFailure) {
-				continue
-			}
-
-			// check for key not found
-			if strings.Contains(errString, keyNotFound) {
-				return nil, KeyNotFoundError{key, errString}
-			}
-
-			// check for authentication error
-			if strings.Contains(errString, authError) {
-				return nil, AuthError{errString}
-			}
-		}
-
-		break
-	}
-
-	return bytes, err
-}
-
-// Encodes a byte array to a base64 string.
-func encodeToBase64(bytes []byte) string {
-	return base64.StdEncoding.EncodeToString(bytes)
-}
-
-// Encodes key-value pairs to base64 and writes them to KV in multiple bulk requests.
+// Encodes key-value pairs and writes them to a store in bulk.
 // Returns the list of human-readable names of successful writes.
-func EncodeAndWriteKVBulk(ctx context.Context, cfapi *cloudflare.API,
-	kvs []WriteRequest, namespaceID string, panicOversized bool) ([]string, error) {
-	var bulkWrites []cloudflare.WorkersKVBulkWriteRequest
-	var bulkWrite []*cloudflare.WorkersKVPair
+func EncodeAndWriteKVBulk(ctx context.Context, store Store,
+	kvs []WriteRequest, ns Namespace, panicOversized bool) ([]string, error) {
+	var pairs []*Pair
 	var successfulWrites []string
-	var totalSize, totalKeys int64
 
 	for _, kv := range kvs {
 		if unencodedSize := int64(len(kv.GetValue())); unencodedSize > util.MaxFileSize {
@@ -115,15 +55,9 @@ func EncodeAndWriteKVBulk(ctx context.Context, cfapi *cloudflare.API,
 			}
 			continue
 		}
-		// Note that after encoding in base64 the size may get larger, but after decoding
-		// it will be reduced, so it is okay if the size is larger than util.MaxFileSize after encoding base64.
-		// However, we still need to check for the KV bulk request limit of 100MiB.
-		encodedValue := encodeToBase64(kv.GetValue())
-		size := int64(len(encodedValue))
-		writePair := &cloudflare.WorkersKVPair{
-			Key:    kv.GetKey(),
-			Value:  encodedValue,
-			Base64: true,
+		pair := &Pair{
+			Key:   kv.GetKey(),
+			Value: kv.GetValue(),
 		}
 		if kv.GetMeta() != nil {
 			// Marshal metadata into JSON bytes.
@@ -140,77 +74,44 @@ func EncodeAndWriteKVBulk(ctx context.Context, cfapi *cloudflare.API,
 				}
 				continue
 			}
-			writePair.Metadata = kv.GetMeta()
-			size += metasize
+			pair.Metadata = kv.GetMeta()
 		}
-		if totalSize+size > util.MaxBulkWritePayload || totalKeys == util.MaxBulkKeys {
-			// Create a new bulk since we are over a limit.
-			bulkWrites = append(bulkWrites, bulkWrite)
-			bulkWrite = []*cloudflare.WorkersKVPair{}
-			totalSize = 0
-			totalKeys = 0
-		}
-		bulkWrite = append(bulkWrite, writePair)
+		pairs = append(pairs, pair)
 		successfulWrites = append(successfulWrites, kv.GetName())
-		totalSize += size
-		totalKeys++
 
 		kv.Consumed()
 	}
-	bulkWrites = append(bulkWrites, bulkWrite)
-
-	for i, b := range bulkWrites {
-		log.Printf("writing bulk %d/%d (keys=%d)...\n", i+1, len(bulkWrites), len(b))
-		for j := 0; j < util.MaxKVAttempts; j++ {
-			r, err := cfapi.WriteWorkersKVBulk(ctx, namespaceID, b)
 
-			// check for service failure and retry
-			if err != nil && strings.Contains(err.Error(), serviceFailure) {
-				if j == util.MaxKVAttempts-1 {
-					return nil, err // no more attempts
-				}
-				continue // retry
-			}
-
-			if err = checkSuccess(r, err); err != nil {
-				return nil, err
-			}
-
-			break
-		}
+	if err := store.WriteBulk(ctx, ns, pairs); err != nil {
+		return nil, err
 	}
 
 	return successfulWrites, nil
 }
 
 // Returns all KVs that start with a prefix.
-func listByPrefix(api *cloudflare.API, prefix, namespaceID string) ([]cloudflare.StorageKey, error) {
-	var cursor *string
-	var results []cloudflare.StorageKey
+func listByPrefix(ctx context.Context, store Store, prefix string, ns Namespace) ([]Key, error) {
+	var cursor string
+	var results []Key
 	for {
-		o := cloudflare.ListWorkersKVsOptions{
-			Prefix: &prefix,
-			Cursor: cursor,
-		}
-
-		resp, err := api.ListWorkersKVsWithOptions(context.Background(), namespaceID, o)
+		res, err := store.List(ctx, ns, prefix, cursor)
 		if err != nil {
 			return nil, err
 		}
 
-		results = append(results, resp.Result...)
+		results = append(results, res.Keys...)
 
-		if resp.Cursor == "" {
+		if res.Cursor == "" {
 			return results, nil
 		}
 
-		cursor = &resp.Cursor
+		cursor = res.Cursor
 	}
 }
 
 // Lists by prefix and then returns only the names of the results.
-func listByPrefixNamesOnly(api *cloudflare.API, prefix string, namespaceID string) ([]string, error) {
-	results, err := listByPrefix(api, prefix, namespaceID)
+func listByPrefixNamesOnly(ctx context.Context, store Store, prefix string, ns Namespace) ([]string, error) {
+	results, err := listByPrefix(ctx, store, prefix, ns)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,193 @@
+package kv
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/cdnjs/tools/packages"
+
+	"github.com/pkg/errors"
+)
+
+// Number of files copied at once when renaming a package,
+// files can be up to util.MaxFileSize.
+const copyBatchSize = 50
+
+// PackageExistsError represents a package that already exists in KV.
+type PackageExistsError struct {
+	Name string
+}
+
+// Error is used to satisfy the error interface.
+func (p PackageExistsError) Error() string {
+	return "package already exists: " + p.Name
+}
+
+// The namespaces whose keys are prefixed by `<package>/`.
+var packagePrefixedNamespaces = []Namespace{
+	FilesNamespace,
+	SRIsNamespace,
+	VersionsNamespace,
+	VersionMetadataNamespace,
+}
+
+// The namespaces whose key is the package name.
+var packageKeyedNamespaces = []Namespace{
+	PackagesNamespace,
+	AggregatedMetadataNamespace,
+}
+
+// RemovePackage deletes every entry of a package from KV: the files, SRIs
+// and entries of all its versions, its package entry and its aggregated
+// metadata. Returns the deleted keys by namespace.
+func RemovePackage(ctx context.Context, store Store, pkgName string) (map[Namespace][]string, error) {
+	deleted := make(map[Namespace][]string)
+
+	// delete the files first, so that they are no longer served
+	for _, ns := range packagePrefixedNamespaces {
+		keys, err := listByPrefixNamesOnly(ctx, store, pkgName+"/", ns)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %s", ns)
+		}
+		if err := store.Delete(ctx, ns, keys); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete %s", ns)
+		}
+		deleted[ns] = keys
+		log.Printf("%s: deleted %d keys in %s\n", pkgName, len(keys), ns)
+	}
+
+	for _, ns := range packageKeyedNamespaces {
+		if _, err := store.Read(ctx, ns, pkgName); err != nil {
+			if _, ok := err.(KeyNotFoundError); ok {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to read %s", ns)
+		}
+		if err := store.Delete(ctx, ns, []string{pkgName}); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete %s", ns)
+		}
+		deleted[ns] = []string{pkgName}
+	}
+
+	return deleted, nil
+}
+
+// RenamePackage copies every entry of a package to a new name, which must
+// not exist yet (ex. when a npm package moves under a scope). The files, SRIs
+// and entries of all its versions are copied, the old entries are kept so
+// that existing URLs keep working. The package entry of the old name
+// redirects to the new name, which records the old name as a former name.
+func RenamePackage(ctx context.Context, store Store, oldName, newName string) error {
+	if _, err := GetPackage(ctx, store, newName); err == nil {
+		return PackageExistsError{newName}
+	} else if _, ok := err.(KeyNotFoundError); !ok {
+		return errors.Wrap(err, "failed to get new package")
+	}
+
+	pkg, err := GetPackage(ctx, store, oldName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get package")
+	}
+
+	// copy the files, SRIs and versions before the package entry so that
+	// the new package is complete once visible
+	for _, ns := range packagePrefixedNamespaces {
+		n, err := copyPrefixed(ctx, store, ns, oldName, newName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy %s", ns)
+		}
+		log.Printf("%s: copied %d keys in %s to %s\n", oldName, n, ns, newName)
+	}
+
+	aggPkg, err := getAggregatedMetadata(ctx, store, oldName)
+	if err != nil {
+		if _, ok := err.(KeyNotFoundError); !ok {
+			return errors.Wrap(err, "failed to get aggregated metadata")
+		}
+		log.Printf("%s: aggregated metadata not found, ignoring\n", oldName)
+	} else {
+		aggPkg.Name = &newName
+		aggPkg.RenamedTo = nil
+		aggPkg.FormerNames = appendName(aggPkg.FormerNames, oldName)
+		_, err := modifyAggregatedMetadata(ctx, store, newName, func(*packages.Package) (*packages.Package, error) {
+			return aggPkg, nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to write aggregated metadata")
+		}
+	}
+
+	renamed := *pkg
+	renamed.Name = &newName
+	renamed.RenamedTo = nil
+	renamed.FormerNames = appendName(pkg.FormerNames, oldName)
+	if err := UpdateKVPackage(ctx, store, &renamed); err != nil {
+		return errors.Wrap(err, "failed to write new package")
+	}
+
+	pkg.RenamedTo = &newName
+	if err := UpdateKVPackage(ctx, store, pkg); err != nil {
+		return errors.Wrap(err, "failed to write redirect")
+	}
+	return nil
+}
+
+// Copies the keys prefixed by `<oldName>/` to `<newName>/`, with their
+// values and metadata. The publication manifests are not copied, they
+// record the publication of the old package. Returns the number of
+// copied keys.
+func copyPrefixed(ctx context.Context, store Store, ns Namespace, oldName, newName string) (int, error) {
+	list, err := listByPrefix(ctx, store, oldName+"/", ns)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list")
+	}
+
+	keys := make([]Key, 0, len(list))
+	for _, k := range list {
+		if ns == VersionMetadataNamespace && path.Base(k.Name) == PublishManifestName {
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	for start := 0; start < len(keys); start += copyBatchSize {
+		end := start + copyBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		pairs := make([]*Pair, 0, end-start)
+		for _, k := range keys[start:end] {
+			pair := &Pair{
+				Key:      newName + strings.TrimPrefix(k.Name, oldName),
+				Metadata: k.Metadata,
+			}
+			// SRIs only have metadata
+			if ns != SRIsNamespace {
+				value, err := store.Read(ctx, ns, k.Name)
+				if err != nil {
+					return 0, errors.Wrapf(err, "failed to read %s", k.Name)
+				}
+				pair.Value = value
+			}
+			pairs = append(pairs, pair)
+		}
+
+		if err := store.WriteBulk(ctx, ns, pairs); err != nil {
+			return 0, errors.Wrap(err, "failed to write")
+		}
+	}
+	return len(keys), nil
+}
+
+// Appends a name to a list of names, unless it is already present.
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(append([]string{}, names...), name)
+}
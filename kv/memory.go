@@ -0,0 +1,146 @@
+package kv
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// listPageSize is the maximum number of keys returned in a page
+// by the local stores, matching the Workers KV default.
+const listPageSize = 1000
+
+// MemoryStore is a Store that keeps all entries in memory.
+// It is safe for concurrent use and is intended for tests.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[Namespace]map[string]Pair
+}
+
+// NewMemoryStore creates an empty in-memory Store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[Namespace]map[string]Pair),
+	}
+}
+
+// Read reads an entry from memory.
+func (s *MemoryStore) Read(ctx context.Context, ns Namespace, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.entries[ns][key]
+	if !ok {
+		return nil, KeyNotFoundError{key, "not in memory store"}
+	}
+	return copyBytes(p.Value), nil
+}
+
+// WriteBulk writes a number of pairs to memory.
+func (s *MemoryStore) WriteBulk(ctx context.Context, ns Namespace, pairs []*Pair) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[ns]; !ok {
+		s.entries[ns] = make(map[string]Pair)
+	}
+	for _, p := range pairs {
+		s.entries[ns][p.Key] = Pair{
+			Key:      p.Key,
+			Value:    copyBytes(p.Value),
+			Metadata: copyMetadata(p.Metadata),
+		}
+	}
+	return nil
+}
+
+// WriteIfRevision writes a pair to memory if the revision
+// of the existing entry is the expected one.
+func (s *MemoryStore) WriteIfRevision(ctx context.Context, ns Namespace, pair *Pair, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if actual := revisionOf(s.entries[ns][pair.Key].Metadata); actual != revision {
+		return RevisionMismatchError{pair.Key, revision, actual}
+	}
+
+	if _, ok := s.entries[ns]; !ok {
+		s.entries[ns] = make(map[string]Pair)
+	}
+	s.entries[ns][pair.Key] = Pair{
+		Key:      pair.Key,
+		Value:    copyBytes(pair.Value),
+		Metadata: copyMetadata(pair.Metadata),
+	}
+	return nil
+}
+
+// List lists a page of keys from memory.
+func (s *MemoryStore) List(ctx context.Context, ns Namespace, prefix, cursor string) (*ListResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for name := range s.entries[ns] {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+
+	page, next := paginate(names, cursor)
+
+	res := &ListResult{Cursor: next}
+	for _, name := range page {
+		res.Keys = append(res.Keys, Key{
+			Name:     name,
+			Metadata: copyMetadata(s.entries[ns][name].Metadata),
+		})
+	}
+	return res, nil
+}
+
+// Delete deletes keys from memory.
+func (s *MemoryStore) Delete(ctx context.Context, ns Namespace, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries[ns], key)
+	}
+	return nil
+}
+
+// Sorts names and returns the page following the cursor,
+// as well as the cursor for the next page.
+func paginate(names []string, cursor string) ([]string, string) {
+	sort.Strings(names)
+
+	start := sort.SearchStrings(names, cursor)
+	if start < len(names) && names[start] == cursor {
+		start++
+	}
+
+	end := start + listPageSize
+	if end >= len(names) {
+		return names[start:], ""
+	}
+	return names[start:end], names[end-1]
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+func copyMetadata(m *FileMetadata) *FileMetadata {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	return &c
+}
@@ -0,0 +1,185 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// PublishManifestName is the name of the manifest of the publication of a
+// version, stored in the VersionMetadataNamespace (ex. `a/1.0.0/publish.json`).
+const PublishManifestName = "publish.json"
+
+// PublishManifest is written before any other key of a version is published,
+// it lists the keys to be written and the steps already applied, so that an
+// interrupted publication can be resumed.
+type PublishManifest struct {
+	Package   string                 `json:"package"`
+	Version   string                 `json:"version"`
+	Digest    string                 `json:"digest"`    // hash of the published content
+	Keys      map[Namespace][]string `json:"keys"`      // keys to be written, by namespace
+	Completed []string               `json:"completed"` // names of the applied steps, in order
+	Committed bool                   `json:"committed"`
+}
+
+// PublishStep is a step of a publication. Steps may be applied again if
+// they were interrupted, so they must be idempotent.
+type PublishStep struct {
+	Name  string
+	Apply func(ctx context.Context) error
+}
+
+// NewPublishManifest creates the manifest of the publication of a version,
+// identified by the digest of its content.
+func NewPublishManifest(pkg, version, digest string) *PublishManifest {
+	return &PublishManifest{
+		Package:   pkg,
+		Version:   version,
+		Digest:    digest,
+		Keys:      make(map[Namespace][]string),
+		Completed: make([]string, 0),
+	}
+}
+
+// AddKeys adds keys to be written in a namespace.
+func (m *PublishManifest) AddKeys(ns Namespace, keys ...string) {
+	m.Keys[ns] = append(m.Keys[ns], keys...)
+}
+
+// Returns if a step was applied.
+func (m *PublishManifest) completed(step string) bool {
+	for _, name := range m.Completed {
+		if name == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Gets the key of the publish manifest of a version.
+func publishManifestKey(pkg, version string) string {
+	return path.Join(pkg, version, PublishManifestName)
+}
+
+// GetPublishManifest gets the manifest of the last publication of a version.
+func GetPublishManifest(ctx context.Context, store Store, pkg, version string) (*PublishManifest, error) {
+	bytes, err := store.Read(ctx, VersionMetadataNamespace, publishManifestKey(pkg, version))
+	if err != nil {
+		return nil, err
+	}
+	var m PublishManifest
+	if err := json.Unmarshal(bytes, &m); err != nil {
+		return nil, errors.Wrap(err, "could not parse publish manifest")
+	}
+	return &m, nil
+}
+
+// Writes the manifest of a publication.
+func writePublishManifest(ctx context.Context, store Store, m *PublishManifest) error {
+	v, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal publish manifest")
+	}
+
+	req := &ConsumableWriteRequest{
+		Key:   publishManifestKey(m.Package, m.Version),
+		Value: v,
+	}
+	_, err = EncodeAndWriteKVBulk(ctx, store, []WriteRequest{req}, VersionMetadataNamespace, true)
+	return err
+}
+
+// Publish applies the steps of the publication of a version in order,
+// recording each applied step in the manifest, which is written first
+// and marked as committed once all steps were applied.
+//
+// If a publication of the same content was interrupted, the steps it
+// applied are skipped. If it was committed, nothing is applied. If a
+// publication of other content was interrupted, its files and SRIs which
+// are not part of this publication are deleted.
+// Returns if any step was applied.
+func Publish(ctx context.Context, store Store, m *PublishManifest, steps []PublishStep) (bool, error) {
+	prev, err := GetPublishManifest(ctx, store, m.Package, m.Version)
+	if err != nil {
+		if _, ok := err.(KeyNotFoundError); !ok {
+			return false, errors.Wrap(err, "failed to get publish manifest")
+		}
+		prev = nil
+	}
+
+	if prev != nil && prev.Digest == m.Digest {
+		if prev.Committed {
+			log.Printf("%s: %s is already published\n", m.Package, m.Version)
+			return false, nil
+		}
+		m.Completed = prev.Completed
+		log.Printf("%s: resuming publication of %s after %v\n", m.Package, m.Version, m.Completed)
+	} else if prev != nil && !prev.Committed {
+		// the interrupted publication of other content may have written
+		// files which are not part of this one
+		if err := deleteStaleKeys(ctx, store, prev, m); err != nil {
+			return false, errors.Wrap(err, "failed to delete keys of interrupted publication")
+		}
+	}
+	m.Committed = false
+
+	if err := writePublishManifest(ctx, store, m); err != nil {
+		return false, errors.Wrap(err, "failed to write publish manifest")
+	}
+
+	for _, step := range steps {
+		if m.completed(step.Name) {
+			continue
+		}
+		if err := step.Apply(ctx); err != nil {
+			return true, errors.Wrapf(err, "failed to apply %s", step.Name)
+		}
+		m.Completed = append(m.Completed, step.Name)
+		if err := writePublishManifest(ctx, store, m); err != nil {
+			return true, errors.Wrapf(err, "failed to record %s", step.Name)
+		}
+	}
+
+	m.Committed = true
+	if err := writePublishManifest(ctx, store, m); err != nil {
+		return true, errors.Wrap(err, "failed to commit publish manifest")
+	}
+	log.Printf("%s: committed publication of %s\n", m.Package, m.Version)
+	return true, nil
+}
+
+// The namespaces whose keys only belong to a version, the keys of the
+// other namespaces are updated by each publication.
+var publishVersionNamespaces = []Namespace{
+	FilesNamespace,
+	SRIsNamespace,
+}
+
+// Deletes the files and SRIs of a previous publication which are not
+// written by the next one.
+func deleteStaleKeys(ctx context.Context, store Store, prev, next *PublishManifest) error {
+	for _, ns := range publishVersionNamespaces {
+		written := make(map[string]bool, len(next.Keys[ns]))
+		for _, key := range next.Keys[ns] {
+			written[key] = true
+		}
+
+		stale := make([]string, 0)
+		for _, key := range prev.Keys[ns] {
+			if !written[key] {
+				stale = append(stale, key)
+			}
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		if err := store.Delete(ctx, ns, stale); err != nil {
+			return errors.Wrapf(err, "failed to delete %s", ns)
+		}
+		log.Printf("%s: deleted %d stale keys of %s in %s\n", prev.Package, len(stale), prev.Version, ns)
+	}
+	return nil
+}
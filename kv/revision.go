@@ -0,0 +1,67 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+)
+
+// RevisionMismatchError represents a key that was updated
+// since its revision was read.
+type RevisionMismatchError struct {
+	Key      string
+	Expected int64
+	Actual   int64
+}
+
+// Error is used to satisfy the error interface.
+func (r RevisionMismatchError) Error() string {
+	return fmt.Sprintf("revision mismatch for `%s`: expected %d, got %d", r.Key, r.Expected, r.Actual)
+}
+
+// Gets the revision from the metadata of an entry, 0 if it has none.
+func revisionOf(meta *FileMetadata) int64 {
+	if meta == nil {
+		return 0
+	}
+	return meta.Revision
+}
+
+// Reads the revision of a key, 0 if the key does not exist or has no revision.
+// The metadata is only returned when listing keys.
+func readRevision(ctx context.Context, store Store, ns Namespace, key string) (int64, error) {
+	var cursor string
+	for {
+		res, err := store.List(ctx, ns, key, cursor)
+		if err != nil {
+			return 0, err
+		}
+		for _, k := range res.Keys {
+			if k.Name == key {
+				return revisionOf(k.Metadata), nil
+			}
+		}
+		if res.Cursor == "" {
+			return 0, nil
+		}
+		cursor = res.Cursor
+	}
+}
+
+// Writes a pair with the next revision if the revision of the existing key is
+// the expected one. Only a ConditionalStore can check the revision atomically,
+// other stores (ex. Workers KV, which only lists the metadata of the keys
+// eventually) write the pair unconditionally: the last concurrent update of
+// a key wins.
+func writeIfRevision(ctx context.Context, store Store, ns Namespace, pair *Pair, revision int64) error {
+	meta := FileMetadata{}
+	if pair.Metadata != nil {
+		meta = *pair.Metadata
+	}
+	meta.Revision = revision + 1
+	pair.Metadata = &meta
+
+	if cs, ok := store.(ConditionalStore); ok {
+		return cs.WriteIfRevision(ctx, ns, pair, revision)
+	}
+	return store.WriteBulk(ctx, ns, []*Pair{pair})
+}
@@ -0,0 +1,47 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// SRIManifestName is the name of the manifest of the SRIs of a
+// version, stored in the VersionMetadataNamespace (ex. `a/1.0.0/sri.json`).
+const SRIManifestName = "sri.json"
+
+// Gets the key of the SRI manifest of a version.
+func sriManifestKey(pkg, version string) string {
+	return path.Join(pkg, version, SRIManifestName)
+}
+
+// GetSRIManifest gets the SRIs of the files of a version, by file name.
+func GetSRIManifest(ctx context.Context, store Store, pkg, version string) (map[string]string, error) {
+	bytes, err := store.Read(ctx, VersionMetadataNamespace, sriManifestKey(pkg, version))
+	if err != nil {
+		return nil, err
+	}
+	var sris map[string]string
+	if err := json.Unmarshal(bytes, &sris); err != nil {
+		return nil, errors.Wrap(err, "could not parse SRI manifest")
+	}
+	return sris, nil
+}
+
+// UpdateKVSRIManifest writes the SRIs of the files of a version, by file name,
+// so that all of them can be read at once.
+func UpdateKVSRIManifest(ctx context.Context, store Store, pkg, version string, sris map[string]string) ([]byte, error) {
+	v, err := json.Marshal(sris)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal SRI manifest")
+	}
+
+	req := &ConsumableWriteRequest{
+		Key:   sriManifestKey(pkg, version),
+		Value: v,
+	}
+	_, err = EncodeAndWriteKVBulk(ctx, store, []WriteRequest{req}, VersionMetadataNamespace, true)
+	return v, err
+}
@@ -0,0 +1,107 @@
+package kv
+
+import (
+	"context"
+	"os"
+)
+
+// Namespace identifies one of the logical KV namespaces used by cdnjs.
+// Each Store implementation decides how a Namespace maps to its own storage.
+type Namespace string
+
+const (
+	// VersionsNamespace holds the list of files for each package version.
+	VersionsNamespace Namespace = "versions"
+
+	// PackagesNamespace holds the non-human-readable package metadata.
+	PackagesNamespace Namespace = "packages"
+
+	// AggregatedMetadataNamespace holds the gzipped aggregated package metadata.
+	AggregatedMetadataNamespace Namespace = "aggregated-metadata"
+
+	// FilesNamespace holds the compressed files of each package version.
+	FilesNamespace Namespace = "files"
+
+	// SRIsNamespace holds the SRIs of each file as metadata.
+	SRIsNamespace Namespace = "sris"
+
+	// VersionMetadataNamespace holds the entries stored alongside each
+	// package version: its SRI manifest, its publish manifest and its
+	// takedown marker.
+	VersionMetadataNamespace Namespace = "version-metadata"
+)
+
+// Namespaces lists all the namespaces used by cdnjs.
+var Namespaces = []Namespace{
+	VersionsNamespace,
+	PackagesNamespace,
+	AggregatedMetadataNamespace,
+	FilesNamespace,
+	SRIsNamespace,
+	VersionMetadataNamespace,
+}
+
+// Pair represents a key, its value and its optional metadata.
+type Pair struct {
+	Key      string
+	Value    []byte
+	Metadata *FileMetadata
+}
+
+// Key represents a listed key and its optional metadata.
+type Key struct {
+	Name     string
+	Metadata *FileMetadata
+}
+
+// ListResult is a page of keys returned by Store.List.
+// An empty Cursor means that there are no more keys to list.
+type ListResult struct {
+	Keys   []Key
+	Cursor string
+}
+
+// Store is a key-value storage backend.
+type Store interface {
+	// Read reads the value of a key, returning a KeyNotFoundError
+	// if the key does not exist.
+	Read(ctx context.Context, ns Namespace, key string) ([]byte, error)
+
+	// WriteBulk writes a number of pairs.
+	WriteBulk(ctx context.Context, ns Namespace, pairs []*Pair) error
+
+	// List lists a page of keys starting with a prefix, in lexicographic order.
+	// The cursor is empty for the first page, and is then the cursor
+	// returned by the previous page.
+	List(ctx context.Context, ns Namespace, prefix, cursor string) (*ListResult, error)
+
+	// Delete deletes a number of keys. Keys that do not exist are ignored.
+	Delete(ctx context.Context, ns Namespace, keys []string) error
+}
+
+// ConditionalStore is a Store that can write a key only if it was not
+// updated since it was read, atomically.
+type ConditionalStore interface {
+	Store
+
+	// WriteIfRevision writes a pair if the revision in the metadata of the
+	// existing key is the expected one, 0 if the key does not exist or has
+	// no revision, returning a RevisionMismatchError otherwise.
+	WriteIfRevision(ctx context.Context, ns Namespace, pair *Pair, revision int64) error
+}
+
+// NamespaceIDs maps each Namespace to a Workers KV namespace ID.
+type NamespaceIDs map[Namespace]string
+
+// NamespaceIDsFromEnv reads the Workers KV namespace IDs
+// from the environment.
+func NamespaceIDsFromEnv() NamespaceIDs {
+	return NamespaceIDs{
+		VersionsNamespace:           os.Getenv("WORKERS_KV_VERSIONS_NAMESPACE_ID"),
+		PackagesNamespace:           os.Getenv("WORKERS_KV_PACKAGES_NAMESPACE_ID"),
+		AggregatedMetadataNamespace: os.Getenv("WORKERS_KV_AGGREGATED_METADATA_NAMESPACE_ID"),
+		FilesNamespace:              os.Getenv("FILES_KV_NAMESPACE_ID"),
+		SRIsNamespace:               os.Getenv("WORKERS_KV_SRIS_NAMESPACE_ID"),
+		VersionMetadataNamespace:    os.Getenv("WORKERS_KV_VERSION_METADATA_NAMESPACE_ID"),
+	}
+}
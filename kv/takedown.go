@@ -0,0 +1,132 @@
+package kv
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cdnjs/tools/packages"
+
+	"github.com/pkg/errors"
+)
+
+// TakedownMarkerName is the name of the marker of a version which was taken
+// down, stored in the VersionMetadataNamespace (ex. `a/1.0.0/takedown`) so
+// that the version is not imported again.
+const TakedownMarkerName = "takedown"
+
+// Gets the key of the takedown marker of a version.
+func takedownMarkerKey(pkg, version string) string {
+	return path.Join(pkg, version, TakedownMarkerName)
+}
+
+// GetTakenDownVersions gets the versions of a package which were taken down.
+func GetTakenDownVersions(ctx context.Context, store Store, pkgName string) ([]string, error) {
+	list, err := listByPrefixNamesOnly(ctx, store, pkgName+"/", VersionMetadataNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list versions")
+	}
+
+	versions := make([]string, 0)
+	for _, item := range list {
+		parts := strings.Split(item, "/")
+		if len(parts) == 3 && parts[2] == TakedownMarkerName {
+			versions = append(versions, parts[1])
+		}
+	}
+	return versions, nil
+}
+
+// TakedownResult is the result of the takedown of a version.
+type TakedownResult struct {
+	DeletedKeys   []string // deleted file keys, including their compressed variants
+	LatestVersion *string  // latest version of the package once the version is removed
+}
+
+// TakedownVersion removes a version of a package from KV (ex. after a malware
+// report). It deletes the version's files and SRIs, its version entry, and
+// removes it from the aggregated metadata and the package entry, whose
+// latest version is recomputed from the remaining versions. The package
+// entry is deleted if no version remains. The version is marked as taken
+// down, it is not imported again.
+func TakedownVersion(ctx context.Context, store Store, pkgName, version string) (*TakedownResult, error) {
+	prefix := path.Join(pkgName, version) + "/"
+
+	// mark the version first, so that it is not imported again even
+	// if the takedown is interrupted
+	marker := &ConsumableWriteRequest{
+		Key:   takedownMarkerKey(pkgName, version),
+		Value: []byte(time.Now().UTC().Format(time.RFC3339)),
+	}
+	if _, err := EncodeAndWriteKVBulk(ctx, store, []WriteRequest{marker}, VersionMetadataNamespace, true); err != nil {
+		return nil, errors.Wrap(err, "failed to mark version as taken down")
+	}
+
+	// delete the files first, so that they are no longer served
+	files, err := listByPrefixNamesOnly(ctx, store, prefix, FilesNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list files")
+	}
+	if err := store.Delete(ctx, FilesNamespace, files); err != nil {
+		return nil, errors.Wrap(err, "failed to delete files")
+	}
+	log.Printf("%s: deleted %d files of %s\n", pkgName, len(files), version)
+
+	sris, err := listByPrefixNamesOnly(ctx, store, prefix, SRIsNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list SRIs")
+	}
+	if err := store.Delete(ctx, SRIsNamespace, sris); err != nil {
+		return nil, errors.Wrap(err, "failed to delete SRIs")
+	}
+
+	if err := store.Delete(ctx, VersionsNamespace, []string{path.Join(pkgName, version)}); err != nil {
+		return nil, errors.Wrap(err, "failed to delete version")
+	}
+	manifests := []string{
+		sriManifestKey(pkgName, version),
+		publishManifestKey(pkgName, version),
+	}
+	if err := store.Delete(ctx, VersionMetadataNamespace, manifests); err != nil {
+		return nil, errors.Wrap(err, "failed to delete version manifests")
+	}
+
+	latest, err := GetLatestVersion(ctx, store, pkgName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get latest version")
+	}
+
+	pkg, err := GetPackage(ctx, store, pkgName)
+	if err != nil {
+		if _, ok := err.(KeyNotFoundError); !ok {
+			return nil, errors.Wrap(err, "failed to get package")
+		}
+		log.Printf("%s: package entry not found, ignoring\n", pkgName)
+		pkg = &packages.Package{Name: &pkgName}
+	} else if latest == nil {
+		// the package entry requires a version
+		log.Printf("%s: no version left, deleting package entry\n", pkgName)
+		if err := store.Delete(ctx, PackagesNamespace, []string{pkgName}); err != nil {
+			return nil, errors.Wrap(err, "failed to delete package")
+		}
+	} else {
+		pkg.Version = latest
+		if err := UpdateKVPackage(ctx, store, pkg); err != nil {
+			return nil, errors.Wrap(err, "failed to update package")
+		}
+	}
+
+	if _, _, err := RemoveVersionFromAggregatedMetadata(ctx, store, pkg, version); err != nil {
+		return nil, errors.Wrap(err, "failed to update aggregated metadata")
+	}
+
+	if files == nil {
+		files = make([]string, 0)
+	}
+	return &TakedownResult{
+		DeletedKeys:   files,
+		LatestVersion: latest,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/cdnjs/tools/compress"
+	"github.com/cdnjs/tools/sri"
+
+	"github.com/pkg/errors"
+)
+
+// The encodings of the files that are tried in order to read their
+// content, the raw file is read if none exist.
+var verifyEncodings = []string{
+	compress.Gzip{}.Ext(),
+	compress.Brotli{}.Ext(),
+	compress.Zstd{}.Ext(),
+}
+
+// The extensions of the files which had an SRI before every file had one,
+// versions without SRI manifest were published then.
+var legacySRIExtensions = map[string]bool{
+	".js":  true,
+	".css": true,
+	".map": true,
+}
+
+// SRIMismatch represents a file whose content does not match its SRI.
+type SRIMismatch struct {
+	Key      string
+	Expected string
+	Actual   string
+}
+
+// String represents the mismatch as a human-readable text.
+func (m SRIMismatch) String() string {
+	return fmt.Sprintf("%s: expected `%s`, got `%s`", m.Key, m.Expected, m.Actual)
+}
+
+// SRIReport is the result of the verification of the SRIs of a package.
+type SRIReport struct {
+	Package      string
+	Checked      int           // number of files whose SRI was verified
+	Mismatches   []SRIMismatch // files whose content does not match their SRI
+	MissingSRIs  []string      // files without SRI
+	MissingFiles []string      // files of a version without content
+	OrphanedSRIs []string      // SRIs of files that are not in any version
+	Unsigned     []string      // files of versions published before every file had an SRI, without SRI
+}
+
+// OK returns if no problem was found, the unsigned files of old
+// versions are not a problem.
+func (r *SRIReport) OK() bool {
+	return len(r.Mismatches) == 0 && len(r.MissingSRIs) == 0 &&
+		len(r.MissingFiles) == 0 && len(r.OrphanedSRIs) == 0
+}
+
+// VerifySRIs verifies that the files of each version of a package match
+// the SRIs written to KV, by recomputing them from the stored content.
+func VerifySRIs(ctx context.Context, store Store, pkg string) (*SRIReport, error) {
+	sris, err := listSRIs(ctx, store, pkg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list SRIs")
+	}
+
+	versions, err := GetVersions(ctx, store, pkg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get versions")
+	}
+
+	report := &SRIReport{
+		Package:      pkg,
+		Mismatches:   make([]SRIMismatch, 0),
+		MissingSRIs:  make([]string, 0),
+		MissingFiles: make([]string, 0),
+		OrphanedSRIs: make([]string, 0),
+		Unsigned:     make([]string, 0),
+	}
+
+	for _, version := range versions {
+		files, err := GetVersion(ctx, store, path.Join(pkg, version))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get version %s", version)
+		}
+
+		legacy := false
+		if _, err := store.Read(ctx, VersionMetadataNamespace, sriManifestKey(pkg, version)); err != nil {
+			if _, ok := err.(KeyNotFoundError); !ok {
+				return nil, errors.Wrapf(err, "failed to get SRI manifest of %s", version)
+			}
+			legacy = true
+		}
+
+		for _, file := range files {
+			key := path.Join(pkg, version, file)
+			expected, ok := sris[key]
+			delete(sris, key)
+
+			content, err := readFileContent(ctx, store, key)
+			if err != nil {
+				if _, ok := err.(KeyNotFoundError); ok {
+					report.MissingFiles = append(report.MissingFiles, key)
+					continue
+				}
+				return nil, errors.Wrapf(err, "failed to read %s", key)
+			}
+
+			if !ok || expected == "" {
+				if legacy && !legacySRIExtensions[path.Ext(file)] {
+					report.Unsigned = append(report.Unsigned, key)
+				} else {
+					report.MissingSRIs = append(report.MissingSRIs, key)
+				}
+				continue
+			}
+
+			report.Checked++
+			if actual := calculateSRILike(content, expected); actual != expected {
+				report.Mismatches = append(report.Mismatches, SRIMismatch{key, expected, actual})
+			}
+		}
+	}
+
+	for key := range sris {
+		report.OrphanedSRIs = append(report.OrphanedSRIs, key)
+	}
+	sort.Strings(report.OrphanedSRIs)
+
+	return report, nil
+}
+
+// Lists the SRIs of the files of a package, by key.
+func listSRIs(ctx context.Context, store Store, pkg string) (map[string]string, error) {
+	keys, err := listByPrefix(ctx, store, pkg+"/", SRIsNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	sris := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if k.Metadata != nil {
+			sris[k.Name] = k.Metadata.SRI
+		} else {
+			sris[k.Name] = ""
+		}
+	}
+	return sris, nil
+}
+
+// Reads the uncompressed content of a file from the first encoding
+// present in KV, or the raw file.
+func readFileContent(ctx context.Context, store Store, key string) ([]byte, error) {
+	for _, ext := range verifyEncodings {
+		compressed, err := store.Read(ctx, FilesNamespace, key+ext)
+		if err != nil {
+			if _, ok := err.(KeyNotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+
+		var out bytes.Buffer
+		if err := compress.Decode(ext, &out, bytes.NewReader(compressed)); err != nil {
+			return nil, errors.Wrapf(err, "could not decode %s", key+ext)
+		}
+		return out.Bytes(), nil
+	}
+	return store.Read(ctx, FilesNamespace, key)
+}
+
+// Calculates the SRI of the content with the algorithms of the
+// expected SRI, which may differ from the configured ones.
+func calculateSRILike(content []byte, expected string) string {
+	algorithms, err := sri.AlgorithmsOf(expected)
+	if err != nil {
+		return sri.CalculateSRI(content)
+	}
+	actual, err := sri.CalculateSRIWithAlgorithms(content, algorithms)
+	if err != nil {
+		return sri.CalculateSRI(content)
+	}
+	return actual
+}
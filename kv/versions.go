@@ -6,15 +6,15 @@ import (
 	"path"
 	"strings"
 
+	"github.com/cdnjs/tools/packages"
 	"github.com/cdnjs/tools/util"
 
-	cloudflare "github.com/cloudflare/cloudflare-go"
 	"github.com/pkg/errors"
 )
 
 // GetVersions gets the list of KV version keys for a particular package.
-func GetVersions(api *cloudflare.API, pckgname string) ([]string, error) {
-	list, err := listByPrefixNamesOnly(api, pckgname+"/", versionsNamespaceID)
+func GetVersions(ctx context.Context, store Store, pckgname string) ([]string, error) {
+	list, err := listByPrefixNamesOnly(ctx, store, pckgname+"/", VersionsNamespace)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list versions")
 	}
@@ -28,9 +28,71 @@ func GetVersions(api *cloudflare.API, pckgname string) ([]string, error) {
 	return versions, nil
 }
 
+// GetLatestVersion gets the latest stable version of a package among the
+// versions with files, the one its package entry points to. The versions
+// in withFiles are known to have files, even if not listed in KV yet.
+// Returns nil if no version has files.
+func GetLatestVersion(ctx context.Context, store Store, pckgname string, withFiles ...string) (*string, error) {
+	versions, err := GetVersions(ctx, store, pckgname)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get versions")
+	}
+	known := make(map[string]bool, len(withFiles))
+	for _, version := range withFiles {
+		known[version] = true
+		versions = append(versions, version)
+	}
+
+	return latestVersion(versions, func(version string) (bool, error) {
+		if known[version] {
+			return true, nil
+		}
+		files, err := GetVersion(ctx, store, path.Join(pckgname, version))
+		if err != nil {
+			if _, ok := err.(KeyNotFoundError); ok {
+				// deleted version still listed
+				return false, nil
+			}
+			return false, err
+		}
+		return len(files) > 0, nil
+	})
+}
+
+// Picks the latest stable version among the versions with files,
+// checking the candidates from the latest one.
+func latestVersion(versions []string, hasFiles func(version string) (bool, error)) (*string, error) {
+	candidates := append([]string(nil), versions...)
+	for {
+		latest := packages.GetLatestStableVersion(candidates)
+		if latest == nil {
+			return nil, nil
+		}
+		ok, err := hasFiles(*latest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get version %s", *latest)
+		}
+		if ok {
+			return latest, nil
+		}
+
+		rest := make([]string, 0, len(candidates))
+		for _, version := range candidates {
+			if version != *latest {
+				rest = append(rest, version)
+			}
+		}
+		if len(rest) == len(candidates) {
+			// not one of the candidates, should not happen
+			return nil, errors.Errorf("latest version %s not found", *latest)
+		}
+		candidates = rest
+	}
+}
+
 // // GetVersion gets metadata for a particular version.
-func GetVersion(ctx context.Context, api *cloudflare.API, key string) ([]string, error) {
-	bytes, err := read(api, key, versionsNamespaceID)
+func GetVersion(ctx context.Context, store Store, key string) ([]string, error) {
+	bytes, err := store.Read(ctx, VersionsNamespace, key)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +118,8 @@ func updateVersionRequest(pkg, version string, files []string) WriteRequest {
 
 // // Updates KV with new version's metadata.
 // // The []string of `files` will already contain the optimized/minified files by now.
-func UpdateKVVersion(ctx context.Context, api *cloudflare.API, pkg, version string, files []string) ([]byte, error) {
+func UpdateKVVersion(ctx context.Context, store Store, pkg, version string, files []string) ([]byte, error) {
 	req := updateVersionRequest(pkg, version, files)
-	_, err := EncodeAndWriteKVBulk(ctx, api, []WriteRequest{req}, versionsNamespaceID, true)
+	_, err := EncodeAndWriteKVBulk(ctx, store, []WriteRequest{req}, VersionsNamespace, true)
 	return req.GetValue(), err
 }
@@ -0,0 +1,157 @@
+package npm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdnjs/tools/util"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTimeout is the default timeout of a registry request.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultMaxAttempts is the default number of attempts of a registry request
+	// failing because of a network error, rate limiting or a server error.
+	DefaultMaxAttempts = 3
+)
+
+// Client is an npm registry client.
+type Client struct {
+	// RegistryURL is the base URL of the registry (ex. https://registry.npmjs.org).
+	RegistryURL string
+
+	// DownloadsURL is the base URL of the downloads API (ex. https://api.npmjs.org).
+	DownloadsURL string
+
+	// Token is the bearer token sent to the registry, if any.
+	Token string
+
+	// MaxAttempts is the maximum number of attempts of a request.
+	MaxAttempts int
+
+	// RetryDelay is the delay before the first retry, doubled for each next retry.
+	RetryDelay time.Duration
+
+	httpClient *http.Client
+}
+
+// NewClient creates a registry client with a request timeout.
+func NewClient(registryURL, downloadsURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		RegistryURL:  strings.TrimSuffix(registryURL, "/"),
+		DownloadsURL: strings.TrimSuffix(downloadsURL, "/"),
+		Token:        token,
+		MaxAttempts:  DefaultMaxAttempts,
+		RetryDelay:   time.Second,
+		httpClient:   &http.Client{Timeout: timeout},
+	}
+}
+
+// NewClientFromEnv creates a registry client configured by the
+// NPM_REGISTRY_URL, NPM_DOWNLOADS_URL, NPM_TOKEN and NPM_TIMEOUT (in seconds)
+// environment variables, defaulting to the public npm registry.
+func NewClientFromEnv() *Client {
+	registryURL := util.GetProtocol() + "://registry.npmjs.org"
+	if v, ok := os.LookupEnv("NPM_REGISTRY_URL"); ok {
+		registryURL = v
+	}
+
+	downloadsURL := util.GetProtocol() + "://api.npmjs.org"
+	if v, ok := os.LookupEnv("NPM_DOWNLOADS_URL"); ok {
+		downloadsURL = v
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := os.LookupEnv("NPM_TIMEOUT"); ok {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			timeout = time.Duration(seconds) * time.Second
+		} else {
+			log.Printf("invalid NPM_TIMEOUT `%s`, using %s\n", v, timeout)
+		}
+	}
+
+	return NewClient(registryURL, downloadsURL, os.Getenv("NPM_TOKEN"), timeout)
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+)
+
+// DefaultClient gets the registry client configured from the environment.
+func DefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = NewClientFromEnv()
+	})
+	return defaultClient
+}
+
+// Gets the registry URL for a package, encoding the slash of scoped packages
+// (ex. @scope/name -> @scope%2fname).
+func (c *Client) packageURL(name string) string {
+	return c.RegistryURL + "/" + strings.Replace(name, "/", "%2f", 1)
+}
+
+// Determines if a request can be retried given its response status code.
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Sends a GET request, retrying on network errors, rate limiting and
+// server errors. Returns the status code and the body of the last attempt.
+func (c *Client) get(ctx context.Context, url string, auth bool) (int, []byte, error) {
+	var lastErr error
+	delay := c.RetryDelay
+
+	for i := 0; i < c.MaxAttempts; i++ {
+		if i > 0 {
+			log.Printf("retrying %s in %s: %s\n", url, delay, lastErr)
+			select {
+			case <-ctx.Done():
+				return 0, nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "could not create request")
+		}
+		if auth && c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if isRetryable(resp.StatusCode) && i < c.MaxAttempts-1 {
+			lastErr = fmt.Errorf("%s returned %d", url, resp.StatusCode)
+			continue
+		}
+		return resp.StatusCode, body, nil
+	}
+
+	return 0, nil, errors.Wrapf(lastErr, "failed after %d attempts", c.MaxAttempts)
+}
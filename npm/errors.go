@@ -0,0 +1,38 @@
+package npm
+
+import (
+	"fmt"
+)
+
+// NotFoundError represents a package that does not exist in the registry.
+type NotFoundError struct {
+	Name string
+}
+
+// Error is used to satisfy the error interface.
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("npm package not found: %s", e.Name)
+}
+
+// RateLimitedError represents a registry request that kept being
+// rate limited after all attempts.
+type RateLimitedError struct {
+	Name string
+}
+
+// Error is used to satisfy the error interface.
+func (e RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by npm registry: %s", e.Name)
+}
+
+// MalformedDocumentError represents a registry document
+// that could not be understood.
+type MalformedDocumentError struct {
+	Name   string
+	Reason string
+}
+
+// Error is used to satisfy the error interface.
+func (e MalformedDocumentError) Error() string {
+	return fmt.Sprintf("malformed npm registry document for %s: %s", e.Name, e.Reason)
+}
@@ -4,14 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/cdnjs/tools/packages"
-	"github.com/cdnjs/tools/util"
 	"github.com/cdnjs/tools/version"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
 )
 
 // Registry contains metadata about a particular npm package.
@@ -27,74 +28,238 @@ type MonthlyDownload struct {
 	Downloads uint `json:"downloads"`
 }
 
-// Exists determines if an npm package exists.
-func Exists(name string) bool {
-	resp, err := http.Get(util.GetProtocol() + "://registry.npmjs.org/" + name)
-	util.Check(err)
-	return resp.StatusCode == http.StatusOK
+// SkippedVersion represents an npm version that will not be imported.
+type SkippedVersion struct {
+	Version string
+	Date    time.Time
+	Reason  string
+}
+
+// Keys of the `time` registry field that are not versions.
+var timeStampKeys = map[string]bool{
+	"created":     true,
+	"modified":    true,
+	"unpublished": true,
+}
+
+// Exists determines if an npm package exists using the DefaultClient.
+func Exists(name string) (bool, error) {
+	return DefaultClient().Exists(context.Background(), name)
+}
+
+// Exists determines if an npm package exists. It returns a RateLimitedError
+// if the registry kept rate limiting the request.
+func (c *Client) Exists(ctx context.Context, name string) (bool, error) {
+	status, _, err := c.get(ctx, c.packageURL(name), true)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get %s from registry", name)
+	}
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusTooManyRequests:
+		return false, RateLimitedError{name}
+	default:
+		return false, errors.Errorf("registry returned %d for %s", status, name)
+	}
+}
+
+// GetMonthlyDownload gets the MonthlyDownload for a particular
+// npm package using the DefaultClient.
+func GetMonthlyDownload(name string) (MonthlyDownload, error) {
+	return DefaultClient().GetMonthlyDownload(context.Background(), name)
 }
 
 // GetMonthlyDownload uses the npm API to get the MonthlyDownload
 // for a particular npm package.
-func GetMonthlyDownload(name string) MonthlyDownload {
-	resp, err := http.Get(util.GetProtocol() + "://api.npmjs.org/downloads/point/last-month/" + name)
-	util.Check(err)
+func (c *Client) GetMonthlyDownload(ctx context.Context, name string) (MonthlyDownload, error) {
+	var counts MonthlyDownload
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	util.Check(err)
+	status, body, err := c.get(ctx, c.DownloadsURL+"/downloads/point/last-month/"+name, false)
+	if err != nil {
+		return counts, errors.Wrapf(err, "failed to get downloads of %s", name)
+	}
+	if status != http.StatusOK {
+		return counts, errors.Errorf("downloads API returned %d for %s", status, name)
+	}
 
-	var counts MonthlyDownload
-	util.Check(json.Unmarshal(body, &counts))
-	return counts
+	if err := json.Unmarshal(body, &counts); err != nil {
+		return counts, MalformedDocumentError{name, err.Error()}
+	}
+	return counts, nil
 }
 
 // GetVersions gets all of the versions associated with an npm package,
-// as well as the latest version based on the `latest` tag.
-func GetVersions(ctx context.Context, config *packages.Autoupdate) ([]version.Version, *string) {
-	name := *config.Target
-	resp, err := http.Get(util.GetProtocol() + "://registry.npmjs.org/" + name)
-	util.Check(err)
+// the versions that were skipped because they are deprecated, unpublished or
+// not reachable from the configured dist-tags, as well as the latest version
+// based on the `latest` tag, using the DefaultClient.
+func GetVersions(ctx context.Context, config *packages.Autoupdate) ([]version.Version, []SkippedVersion, *string, error) {
+	return DefaultClient().GetVersions(ctx, config)
+}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	util.Check(err)
+// GetVersions gets the versions of an npm package from the registry.
+// It returns a NotFoundError if the package does not exist, a RateLimitedError
+// if the registry kept rate limiting the request, and a MalformedDocumentError
+// if the registry document could not be understood.
+func (c *Client) GetVersions(ctx context.Context, config *packages.Autoupdate) ([]version.Version, []SkippedVersion, *string, error) {
+	name := *config.Target
+	status, body, err := c.get(ctx, c.packageURL(name), true)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "failed to get %s from registry", name)
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil, nil, NotFoundError{name}
+	case http.StatusTooManyRequests:
+		return nil, nil, nil, RateLimitedError{name}
+	default:
+		return nil, nil, nil, errors.Errorf("registry returned %d for %s", status, name)
+	}
 
 	var r Registry
-	util.Check(json.Unmarshal(body, &r))
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, nil, nil, MalformedDocumentError{name, err.Error()}
+	}
+
+	distTags := reachableDistTags(name, config, r.DistTags)
 
 	versions := make([]version.Version, 0)
+	skipped := make([]SkippedVersion, 0)
 	for k, v := range r.Versions {
-		if v, ok := v.(map[string]interface{}); ok {
-			dist := v["dist"].(map[string]interface{})
-			tarball := dist["tarball"].(string)
-
-			if timeInt, ok := r.TimeStamps[k]; ok {
-				if timeStr, ok := timeInt.(string); ok {
-					// parse time.Time from time stamp
-					timeStamp, err := time.Parse(time.RFC3339, timeStr)
-					util.Check(err)
-
-					if !version.IsVersionIgnored(config, k) {
-						versions = append(versions, version.Version{
-							Version: k,
-							Tarball: tarball,
-							Date:    timeStamp,
-							Source:  "npm",
-						})
-					} else {
-						log.Printf("%s: version %s is ignored\n", name, k)
-					}
-					continue
-				}
+		meta, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		parsed, err := parseVersion(r, k, meta)
+		if err != nil {
+			return nil, nil, nil, MalformedDocumentError{name, err.Error()}
+		}
+
+		if version.IsVersionIgnored(config, k) {
+			log.Printf("%s: version %s is ignored\n", name, k)
+			continue
+		}
+
+		if msg, ok := meta["deprecated"].(string); ok && msg != "" {
+			log.Printf("%s: version %s is deprecated, skipping\n", name, k)
+			skipped = append(skipped, SkippedVersion{k, parsed.Date, "deprecated: " + msg})
+			continue
+		}
+
+		if distTags != nil && !isReachable(k, distTags) {
+			log.Printf("%s: version %s is not reachable from dist-tags %v, skipping\n", name, k, config.DistTags)
+			skipped = append(skipped, SkippedVersion{k, parsed.Date, "not reachable from dist-tags"})
+			continue
+		}
+
+		versions = append(versions, *parsed)
+	}
+
+	// versions with a time stamp but no metadata have been unpublished
+	for k, timeInt := range r.TimeStamps {
+		if _, ok := timeStampKeys[k]; ok {
+			continue
+		}
+		if _, ok := r.Versions[k]; ok {
+			continue
+		}
+		if timeStr, ok := timeInt.(string); ok {
+			if timeStamp, err := time.Parse(time.RFC3339, timeStr); err == nil {
+				log.Printf("%s: version %s is unpublished, skipping\n", name, k)
+				skipped = append(skipped, SkippedVersion{k, timeStamp, "unpublished"})
 			}
-			panic(fmt.Errorf("no time stamp for npm version %s/%s", name, k))
 		}
 	}
 
 	// attempt to get latest version according to npm
 	if latest, ok := r.DistTags["latest"]; ok {
-		return versions, &latest
+		return versions, skipped, &latest, nil
+	}
+	return versions, skipped, nil, nil
+}
+
+// Parses the tarball URL, integrity and time stamp of a version.
+func parseVersion(r Registry, k string, meta map[string]interface{}) (*version.Version, error) {
+	dist, ok := meta["dist"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no dist for version %s", k)
+	}
+	tarball, ok := dist["tarball"].(string)
+	if !ok || tarball == "" {
+		return nil, fmt.Errorf("no tarball for version %s", k)
+	}
+
+	// older versions only have a sha1 shasum, both are optional
+	integrity, _ := dist["integrity"].(string)
+	shasum, _ := dist["shasum"].(string)
+
+	timeStr, ok := r.TimeStamps[k].(string)
+	if !ok {
+		return nil, fmt.Errorf("no time stamp for version %s", k)
+	}
+	timeStamp, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time stamp for version %s: %s", k, err)
+	}
+
+	return &version.Version{
+		Version:   k,
+		Tarball:   tarball,
+		Date:      timeStamp,
+		Source:    "npm",
+		Integrity: integrity,
+		Shasum:    shasum,
+	}, nil
+}
+
+// Gets the semver versions pointed to by the dist-tags configured
+// for the package. Returns nil if imports are not restricted to dist-tags.
+func reachableDistTags(name string, config *packages.Autoupdate, distTags map[string]string) []semver.Version {
+	if len(config.DistTags) == 0 {
+		return nil
+	}
+
+	tagged := make([]semver.Version, 0)
+	for _, tag := range config.DistTags {
+		v, ok := distTags[tag]
+		if !ok {
+			log.Printf("%s: dist-tag %s does not exist\n", name, tag)
+			continue
+		}
+		s, err := semver.Parse(v)
+		if err != nil {
+			log.Printf("%s: dist-tag %s points to non-semver version %s\n", name, tag, v)
+			continue
+		}
+		tagged = append(tagged, s)
+	}
+	return tagged
+}
+
+// Determines if a version is reachable from one of the tagged versions.
+// A version is reachable from a tagged version if it is not greater than it
+// and it is either stable or a prerelease of the same release as the tagged version.
+// For instance, 1.9.0 and 2.0.0-beta.1 are reachable from `next: 2.0.0-beta.2`,
+// while a canary build like 0.0.0-canary.1 is not reachable from `latest: 1.9.0`.
+func isReachable(v string, tagged []semver.Version) bool {
+	s, err := semver.Parse(v)
+	if err != nil {
+		return false
+	}
+	for _, t := range tagged {
+		if s.GT(t) {
+			continue
+		}
+		if len(s.Pre) == 0 {
+			return true
+		}
+		if s.Major == t.Major && s.Minor == t.Minor && s.Patch == t.Patch && len(t.Pre) > 0 {
+			return true
+		}
 	}
-	return versions, nil
+	return false
 }
@@ -0,0 +1,110 @@
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cdnjs/tools/util"
+
+	"github.com/pkg/errors"
+)
+
+// largestFilesInReport is the number of largest files listed in a LimitsReport.
+const largestFilesInReport = 10
+
+// Limits is used to override the default limits of the
+// files published for a version.
+type Limits struct {
+	MaxFiles *int   `json:"maxFiles,omitempty"`
+	MaxSize  *int64 `json:"maxSize,omitempty"`
+}
+
+// Files returns the maximum number of files of a version.
+func (l *Limits) Files() int {
+	if l == nil || l.MaxFiles == nil {
+		return util.MaxVersionFiles
+	}
+	return *l.MaxFiles
+}
+
+// Size returns the maximum total size in bytes of the files of a version.
+func (l *Limits) Size() int64 {
+	if l == nil || l.MaxSize == nil {
+		return util.MaxVersionSize
+	}
+	return *l.MaxSize
+}
+
+// FileSize associates a file with its size in bytes.
+type FileSize struct {
+	File string
+	Size int64
+}
+
+// LimitsReport summarizes the files of a version against the limits of a package.
+type LimitsReport struct {
+	Files    int
+	MaxFiles int
+	Size     int64
+	MaxSize  int64
+	Largest  []FileSize // largest files first
+}
+
+// Exceeded determines if any of the limits is exceeded.
+func (r *LimitsReport) Exceeded() bool {
+	return r.Files > r.MaxFiles || r.Size > r.MaxSize
+}
+
+// String represents the report as a human-readable text.
+func (r *LimitsReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "files: %d (limit %d)\n", r.Files, r.MaxFiles)
+	fmt.Fprintf(&b, "total size: %d bytes (limit %d bytes)\n", r.Size, r.MaxSize)
+	if r.Files > r.MaxFiles {
+		fmt.Fprintf(&b, "file count limit exceeded by %d file(s)\n", r.Files-r.MaxFiles)
+	}
+	if r.Size > r.MaxSize {
+		fmt.Fprintf(&b, "size limit exceeded by %d bytes\n", r.Size-r.MaxSize)
+	}
+	if len(r.Largest) > 0 {
+		fmt.Fprintf(&b, "largest files:\n")
+		for _, f := range r.Largest {
+			fmt.Fprintf(&b, "- %s: %d bytes\n", f.File, f.Size)
+		}
+	}
+	return b.String()
+}
+
+// CheckLimits measures the files of a version in the `base` directory
+// against the limits of the package. The limits can be overridden in
+// the package's `limits` property.
+func (p *Package) CheckLimits(base string, files []NpmFileMoveOp) (*LimitsReport, error) {
+	report := &LimitsReport{
+		Files:    len(files),
+		MaxFiles: p.Limits.Files(),
+		MaxSize:  p.Limits.Size(),
+	}
+
+	sizes := make([]FileSize, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(path.Join(base, f.From))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not stat %s", f.From)
+		}
+		report.Size += info.Size()
+		sizes = append(sizes, FileSize{f.To, info.Size()})
+	}
+
+	sort.SliceStable(sizes, func(i, j int) bool {
+		return sizes[i].Size > sizes[j].Size
+	})
+	if len(sizes) > largestFilesInReport {
+		sizes = sizes[:largestFilesInReport]
+	}
+	report.Largest = sizes
+
+	return report, nil
+}
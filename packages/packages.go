@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path"
@@ -27,15 +28,32 @@ type Autoupdate struct {
 	Target         *string   `json:"target,omitempty"`
 	FileMap        []FileMap `json:"fileMap,omitempty"`
 	IgnoreVersions []string  `json:"ignoreVersions,omitempty"`
+	DistTags       []string  `json:"distTags,omitempty"` // npm only
 }
 
 // Optimization is used to enable/disable optimization
 // for particular file types. By default, we will optimize all files.
 type Optimization struct {
-	JS  *bool `json:"js,omitempty"`
-	CSS *bool `json:"css,omitempty"`
-	PNG *bool `json:"png,omitempty"`
-	JPG *bool `json:"jpg,omitempty"`
+	JS         *bool `json:"js,omitempty"`
+	CSS        *bool `json:"css,omitempty"`
+	PNG        *bool `json:"png,omitempty"`
+	JPG        *bool `json:"jpg,omitempty"`
+	SVG        *bool `json:"svg,omitempty"`
+	ZSTD       *bool `json:"zstd,omitempty"`
+	SourceMaps *bool `json:"sourceMaps,omitempty"`
+	WEBP       *bool `json:"webp,omitempty"`
+	AVIF       *bool `json:"avif,omitempty"`
+
+	Minifier *Minifier `json:"minifier,omitempty"`
+}
+
+// Minifier is used to select the minifier of JavaScript
+// files and its options.
+type Minifier struct {
+	Name     *string `json:"name,omitempty"`
+	Mangle   *bool   `json:"mangle,omitempty"`
+	Compress *bool   `json:"compress,omitempty"`
+	Target   *string `json:"target,omitempty"` // ECMAScript version, ex. es2018
 }
 
 // Js returns if we should optimize JavaScript files.
@@ -58,6 +76,70 @@ func (o *Optimization) Jpg() bool {
 	return o == nil || o.JPG == nil || *o.JPG
 }
 
+// Svg returns if we should optimize SVG files.
+func (o *Optimization) Svg() bool {
+	return o == nil || o.SVG == nil || *o.SVG
+}
+
+// Zstd returns if we should publish zstd compressed files.
+func (o *Optimization) Zstd() bool {
+	return o == nil || o.ZSTD == nil || *o.ZSTD
+}
+
+// PublishSourceMaps returns if we should publish source maps for the minified
+// JavaScript and CSS files. Unlike optimizations, it is disabled by default.
+func (o *Optimization) PublishSourceMaps() bool {
+	return o != nil && o.SourceMaps != nil && *o.SourceMaps
+}
+
+// Webp returns if we should publish WebP derivatives of PNG/JPG/JPEG
+// files. Unlike optimizations, it is disabled by default.
+func (o *Optimization) Webp() bool {
+	return o != nil && o.WEBP != nil && *o.WEBP
+}
+
+// Avif returns if we should publish AVIF derivatives of PNG/JPG/JPEG
+// files. Unlike optimizations, it is disabled by default.
+func (o *Optimization) Avif() bool {
+	return o != nil && o.AVIF != nil && *o.AVIF
+}
+
+// JsMinifier returns the minifier configuration, or nil for the defaults.
+func (o *Optimization) JsMinifier() *Minifier {
+	if o == nil {
+		return nil
+	}
+	return o.Minifier
+}
+
+// Names returns the names of the minifiers to try in order,
+// or nil for the default minifiers.
+func (m *Minifier) Names() []string {
+	if m == nil || m.Name == nil {
+		return nil
+	}
+	return []string{*m.Name}
+}
+
+// ShouldMangle returns if the minifier should mangle names.
+func (m *Minifier) ShouldMangle() bool {
+	return m == nil || m.Mangle == nil || *m.Mangle
+}
+
+// ShouldCompress returns if the minifier should compress the code.
+func (m *Minifier) ShouldCompress() bool {
+	return m == nil || m.Compress == nil || *m.Compress
+}
+
+// GetTarget returns the ECMAScript version targeted by the minifier,
+// or an empty string for the minifier's default.
+func (m *Minifier) GetTarget() string {
+	if m == nil || m.Target == nil {
+		return ""
+	}
+	return *m.Target
+}
+
 // FileMap represents a number of files located
 // under a base path.
 type FileMap struct {
@@ -82,6 +164,7 @@ type Package struct {
 	Authors      []Author      `json:"authors,omitempty"`
 	Autoupdate   *Autoupdate   `json:"autoupdate,omitempty"`
 	Optimization *Optimization `json:"optimization,omitempty"`
+	Limits       *Limits       `json:"limits,omitempty"`
 	Description  *string       `json:"description,omitempty"`
 	Filename     *string       `json:"filename,omitempty"`
 	Homepage     *string       `json:"homepage,omitempty"`
@@ -91,7 +174,9 @@ type Package struct {
 	Repository   *Repository   `json:"repository,omitempty"`
 
 	// additional properties
-	Version *string `json:"version,omitempty"`
+	Version     *string  `json:"version,omitempty"`
+	RenamedTo   *string  `json:"renamedTo,omitempty"`   // new name of a renamed package
+	FormerNames []string `json:"formerNames,omitempty"` // names of the package before it was renamed
 
 	// legacy
 	Author *string `json:"author,omitempty"`
@@ -158,10 +243,46 @@ func (p *Package) RemoveVersion(name string) {
 	p.Assets = newAssets
 }
 
+// IgnoredFile represents a matched file that will not be published.
+type IgnoredFile struct {
+	File   string
+	Reason string
+}
+
+// FileMatches is the result of matching the fileMap of a package
+// against the files of a version.
+type FileMatches struct {
+	// Files are the matched files that will be published.
+	Files []NpmFileMoveOp
+
+	// UnmatchedPatterns are the patterns, joined with their base path,
+	// that did not match any file.
+	UnmatchedPatterns []string
+
+	// Ignored are the matched files that will not be published.
+	Ignored []IgnoredFile
+}
+
 // NpmFilesFrom lists files that match the npm glob pattern in the `base` directory
 // Returns a struct that represent the move semantics
 func (p *Package) NpmFilesFrom(base string) []NpmFileMoveOp {
-	out := make([]NpmFileMoveOp, 0)
+	matches, err := p.MatchFiles(base)
+	util.Check(err) // should have already run before in checker so panic if glob invalid
+
+	for _, f := range matches.Ignored {
+		log.Printf("file %s ignored due to %s\n", f.File, f.Reason)
+	}
+	return matches.Files
+}
+
+// MatchFiles matches the fileMap of the package against the files
+// in the `base` directory.
+func (p *Package) MatchFiles(base string) (*FileMatches, error) {
+	out := &FileMatches{
+		Files:             make([]NpmFileMoveOp, 0),
+		UnmatchedPatterns: make([]string, 0),
+		Ignored:           make([]IgnoredFile, 0),
+	}
 
 	// map used to determine if a file path has already been processed
 	seen := make(map[string]bool)
@@ -172,7 +293,13 @@ func (p *Package) NpmFilesFrom(base string) []NpmFileMoveOp {
 
 			// find files that match glob
 			list, err := util.ListFilesGlob(p.ctx, basePath, pattern)
-			util.Check(err) // should have already run before in checker so panic if glob invalid
+			if err != nil {
+				return nil, err
+			}
+
+			if len(list) == 0 {
+				out.UnmatchedPatterns = append(out.UnmatchedPatterns, path.Join(*fileMap.BasePath, pattern))
+			}
 
 			for _, f := range list {
 				fp := path.Join(basePath, f)
@@ -189,15 +316,18 @@ func (p *Package) NpmFilesFrom(base string) []NpmFileMoveOp {
 					continue
 				}
 
-				// warn for files with sizes exceeding max file size
+				// ignore files with sizes exceeding max file size
 				size := info.Size()
 				if size > util.MaxFileSize {
-					log.Printf("file %s ignored due to byte size (%d > %d)\n", f, size, util.MaxFileSize)
+					out.Ignored = append(out.Ignored, IgnoredFile{
+						File:   f,
+						Reason: fmt.Sprintf("byte size (%d > %d)", size, util.MaxFileSize),
+					})
 					continue
 				}
 
 				// file is ok
-				out = append(out, NpmFileMoveOp{
+				out.Files = append(out.Files, NpmFileMoveOp{
 					From: path.Join(*fileMap.BasePath, f),
 					To:   f,
 				})
@@ -205,7 +335,7 @@ func (p *Package) NpmFilesFrom(base string) []NpmFileMoveOp {
 		}
 	}
 
-	return out
+	return out, nil
 }
 
 // // AllFiles lists all files in the version directory.
@@ -136,9 +136,18 @@ const humanReadableProperties = `
                         "additionalProperties": false
                     }
                 },
+                "distTags": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string",
+                        "minLength": 1
+                    }
+                },
                 "source": {
                     "type": "string",
-                    "pattern": "^git|npm$"
+                    "pattern": "^(git|npm|github-release)$"
                 },
                 "target": {
                     "type": "string",
@@ -167,6 +176,63 @@ const humanReadableProperties = `
                 },
                 "jpg": {
                     "type": "boolean"
+                },
+                "svg": {
+                    "description": "Used to enable/disable the minification of SVG files.",
+                    "type": "boolean"
+                },
+                "zstd": {
+                    "description": "Used to enable/disable the zstd compressed variant of the files.",
+                    "type": "boolean"
+                },
+                "sourceMaps": {
+                    "description": "Used to publish source maps for the minified JavaScript and CSS files. Disabled by default.",
+                    "type": "boolean"
+                },
+                "webp": {
+                    "description": "Used to publish WebP derivatives (ex. logo.png.webp) of the PNG and JPEG files. Disabled by default.",
+                    "type": "boolean"
+                },
+                "avif": {
+                    "description": "Used to publish AVIF derivatives (ex. logo.png.avif) of the PNG and JPEG files. Disabled by default.",
+                    "type": "boolean"
+                },
+                "minifier": {
+                    "description": "Used to select the minifier of JavaScript files and its options. By default, uglify-js is used and terser if it fails.",
+                    "type": "object",
+                    "properties": {
+                        "name": {
+                            "type": "string",
+                            "enum": ["uglify", "terser", "esbuild"]
+                        },
+                        "mangle": {
+                            "type": "boolean"
+                        },
+                        "compress": {
+                            "type": "boolean"
+                        },
+                        "target": {
+                            "description": "The ECMAScript version of the minified files, ex. es2018.",
+                            "type": "string",
+                            "pattern": "^es(5|20[0-9]{2}|next)$"
+                        }
+                    },
+                    "additionalProperties": false
+                }
+            },
+            "additionalProperties": false
+        },
+        "limits": {
+            "description": "Used to override the maximum number of files and total size in bytes of the files published for a version.",
+            "type": "object",
+            "properties": {
+                "maxFiles": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "maxSize": {
+                    "type": "integer",
+                    "minimum": 1
                 }
             },
             "additionalProperties": false
@@ -234,4 +300,18 @@ const nonHumanReadableProperties = `
         "version": {
             "type": "string",
             "minLength": 1
+        },
+        "renamedTo": {
+            "description": "The new name of the library, if it was renamed.",
+            "type": "string",
+            "pattern": "^[a-zA-Z0-9._-]+$"
+        },
+        "formerNames": {
+            "description": "The names of the library before it was renamed.",
+            "type": "array",
+            "uniqueItems": true,
+            "items": {
+                "type": "string",
+                "pattern": "^[a-zA-Z0-9._-]+$"
+            }
         }`
@@ -3,6 +3,7 @@ package sandbox
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -17,8 +18,22 @@ import (
 
 var (
 	DOCKER_IMAGE = os.Getenv("DOCKER_IMAGE")
+
+	// environment variables passed to the sandbox
+	forwardedEnv = []string{"BROTLI_QUALITY", "GZIP_LEVEL", "ZSTD_LEVEL", "SRI_ALGORITHMS"}
 )
 
+// ExitError represents a sandbox that exited with a non-zero status code.
+// The logs of the sandbox are still returned along with this error.
+type ExitError struct {
+	StatusCode int64
+}
+
+// Error is used to satisfy the error interface.
+func (e ExitError) Error() string {
+	return fmt.Sprintf("sandbox exited with status code %d", e.StatusCode)
+}
+
 func Setup() (string, string, error) {
 	tmpDir := os.TempDir()
 	inDir, err := ioutil.TempDir(tmpDir, "in")
@@ -68,9 +83,17 @@ func Run(ctx context.Context, containerName, in, out string) (string, error) {
 		return "", errors.Wrap(err, "could not create client")
 	}
 
+	env := make([]string, 0)
+	for _, name := range forwardedEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+
 	resp, err := cli.ContainerCreate(ctx,
 		&container.Config{
 			Image: DOCKER_IMAGE,
+			Env:   env,
 		},
 		&container.HostConfig{
 			Mounts: []mount.Mount{
@@ -95,13 +118,15 @@ func Run(ctx context.Context, containerName, in, out string) (string, error) {
 		return "", errors.Wrap(err, "could not start container")
 	}
 
+	var exitCode int64
 	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
 		if err != nil {
 			return "", errors.Wrap(err, "failed to wait for container")
 		}
-	case <-statusCh:
+	case status := <-statusCh:
+		exitCode = status.StatusCode
 	}
 
 	opts := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}
@@ -123,5 +148,8 @@ func Run(ctx context.Context, containerName, in, out string) (string, error) {
 		return "", errors.Wrapf(err, "could not remove container %s / %s", resp.ID, containerName)
 	}
 
+	if exitCode != 0 {
+		return buff.String(), ExitError{exitCode}
+	}
 	return buff.String(), nil
 }
@@ -1,14 +1,79 @@
 package sri
 
 import (
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"fmt"
+	"hash"
 	"io/ioutil"
+	"log"
+	"os"
+	"strings"
 
 	"github.com/cdnjs/tools/util"
+
+	"github.com/pkg/errors"
 )
 
+// The hash functions of the supported algorithms.
+var hashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// DefaultAlgorithms are the algorithms of the SRIs when
+// SRI_ALGORITHMS is not set.
+var DefaultAlgorithms = []string{"sha512"}
+
+// Algorithms are the algorithms of the SRIs, read from the
+// comma-separated SRI_ALGORITHMS (ex. `sha384,sha512`).
+var Algorithms = algorithmsFromEnv()
+
+// UnsupportedAlgorithmError represents an unknown SRI algorithm.
+type UnsupportedAlgorithmError struct {
+	Algorithm string
+}
+
+// Error is used to satisfy the error interface.
+func (u UnsupportedAlgorithmError) Error() string {
+	return fmt.Sprintf("unsupported SRI algorithm `%s`", u.Algorithm)
+}
+
+// ParseAlgorithms parses a comma-separated list of algorithms.
+func ParseAlgorithms(s string) ([]string, error) {
+	algorithms := make([]string, 0)
+	for _, a := range strings.Split(s, ",") {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		if _, ok := hashes[a]; !ok {
+			return nil, UnsupportedAlgorithmError{a}
+		}
+		algorithms = append(algorithms, a)
+	}
+	if len(algorithms) == 0 {
+		return nil, errors.New("no SRI algorithm")
+	}
+	return algorithms, nil
+}
+
+// Reads the algorithms from the environment.
+func algorithmsFromEnv() []string {
+	v, ok := os.LookupEnv("SRI_ALGORITHMS")
+	if !ok {
+		return DefaultAlgorithms
+	}
+	algorithms, err := ParseAlgorithms(v)
+	if err != nil {
+		log.Printf("invalid SRI_ALGORITHMS `%s`, using %s: %s\n", v, DefaultAlgorithms, err)
+		return DefaultAlgorithms
+	}
+	return algorithms
+}
+
 // CalculateFileSRI generates a Subresource Integrity string for a particular file.
 func CalculateFileSRI(filepath string, out string) {
 	bytes, err := ioutil.ReadFile(filepath)
@@ -20,12 +85,50 @@ func CalculateFileSRI(filepath string, out string) {
 	util.Check(err)
 }
 
-// CalculateSRI calculates a Subresource Integrity string from bytes.
+// CalculateSRI calculates a Subresource Integrity string from bytes,
+// with the configured algorithms.
 func CalculateSRI(bytes []byte) string {
-	h := sha512.New()
-	_, err := h.Write(bytes)
+	sri, err := CalculateSRIWithAlgorithms(bytes, Algorithms)
 	util.Check(err)
+	return sri
+}
+
+// CalculateSRIWithAlgorithms calculates a Subresource Integrity string from
+// bytes, containing a space-separated hash for each algorithm
+// (ex. `sha384-... sha512-...`).
+func CalculateSRIWithAlgorithms(bytes []byte, algorithms []string) (string, error) {
+	sris := make([]string, 0, len(algorithms))
+	for _, a := range algorithms {
+		newHash, ok := hashes[a]
+		if !ok {
+			return "", UnsupportedAlgorithmError{a}
+		}
+		h := newHash()
+		if _, err := h.Write(bytes); err != nil {
+			return "", errors.Wrapf(err, "could not hash with %s", a)
+		}
+		sris = append(sris, fmt.Sprintf("%s-%s", a, base64.StdEncoding.EncodeToString(h.Sum(nil))))
+	}
+	return strings.Join(sris, " "), nil
+}
 
-	sri := base64.StdEncoding.EncodeToString(h.Sum(nil))
-	return fmt.Sprintf("sha512-%s", sri)
+// AlgorithmsOf returns the algorithms of the hashes of a
+// Subresource Integrity string, in order.
+func AlgorithmsOf(integrity string) ([]string, error) {
+	algorithms := make([]string, 0)
+	for _, h := range strings.Fields(integrity) {
+		i := strings.Index(h, "-")
+		if i < 0 {
+			return nil, errors.Errorf("invalid SRI hash `%s`", h)
+		}
+		a := h[:i]
+		if _, ok := hashes[a]; !ok {
+			return nil, UnsupportedAlgorithmError{a}
+		}
+		algorithms = append(algorithms, a)
+	}
+	if len(algorithms) == 0 {
+		return nil, errors.New("empty SRI")
+	}
+	return algorithms, nil
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/audit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	log.Printf("optimizing files\n")
+	audit.LogWarning(audit.Warning{Kind: "minify-js", File: "dist/a.js", Message: "could not minify a.js: terser: unexpected token"})
+	log.Printf("warning: not structured\n")
+	audit.LogWarning(audit.Warning{Kind: "minify-css", File: "b.css", Message: "multi\nline"})
+
+	assert.Equal(t, []audit.Warning{
+		{Kind: "minify-js", File: "dist/a.js", Message: "could not minify a.js: terser: unexpected token"},
+		{Kind: "minify-css", File: "b.css", Message: "multi\nline"},
+	}, audit.ParseWarnings(logs.String()))
+
+	content := audit.ProcessingLog(logs.String()).String()
+	assert.Equal(t, "warnings:\n"+
+		"- minify-js dist/a.js: could not minify a.js: terser: unexpected token\n"+
+		"- minify-css b.css: multi\nline\n"+
+		"\n"+logs.String(), content)
+}
+
+func TestProcessingLogWithoutWarnings(t *testing.T) {
+	assert.Equal(t, "processed a\n", audit.ProcessingLog("processed a\n").String())
+	assert.Equal(t, []audit.Warning{}, audit.ParseWarnings("processed a\n"))
+}
+
+func TestProcessingLogLength(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	audit.LogWarning(audit.Warning{Kind: "minify-js", File: "a.js", Message: "could not minify a.js"})
+	log.Printf("%s\n", strings.Repeat("a", audit.MAX_LOGS_LENGTH))
+
+	content := audit.ProcessingLog(logs.String()).String()
+	assert.Len(t, content, audit.MAX_LOGS_LENGTH)
+	assert.True(t, strings.HasPrefix(content, "warnings:\n- minify-js a.js: could not minify a.js\n"))
+}
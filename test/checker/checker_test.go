@@ -19,31 +19,12 @@ func createFakeBotPath() string {
 		panic(err)
 	}
 
-	// create fake glob that will return all the files all the time
-	{
-		dir := path.Join(botpath, "glob")
-		if err := os.Mkdir(dir, os.ModePerm); err != nil {
-			panic(err)
-		}
-
-		content := []byte(`#!/usr/bin/env node
-			const fs = require('fs');
-			fs.readdirSync(process.cwd()).forEach(file => {
-				console.log(file);
-			});
-		`)
-		err := ioutil.WriteFile(path.Join(dir, "index.js"), content, 0777)
-		if err != nil {
-			panic(err)
-		}
-
-	}
-
 	return botpath
 }
 
-// start a local proxy server and run the checker binary
-func runChecker(fakeBotPath string, proxy string, validatePath bool, args ...string) string {
+// run the checker binary against the npm and GitHub stand-in server
+// listening on addr
+func runChecker(fakeBotPath string, addr string, validatePath bool, args ...string) string {
 	// used to avoid validating the package's path
 	if !validatePath {
 		args = append([]string{"-no-path-validation"}, args...)
@@ -51,7 +32,9 @@ func runChecker(fakeBotPath string, proxy string, validatePath bool, args ...str
 
 	cmd := exec.Command("../../bin/checker", args...)
 	cmd.Env = append(os.Environ(),
-		"HTTP_PROXY="+proxy,
+		"NPM_REGISTRY_URL=http://"+addr,
+		"NPM_DOWNLOADS_URL=http://"+addr,
+		"GITHUB_API_URL=http://"+addr,
 		"BOT_BASE_PATH="+fakeBotPath,
 	)
 
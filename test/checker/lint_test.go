@@ -29,37 +29,37 @@ const (
 	popularRepo    = "user/popularRepo"
 )
 
-// fakes the npm api and GitHub api for testing purposes
+// fakes the npm registry, npm downloads API and GitHub API for testing purposes
 func fakeNpmGitHubHandlerLint(w http.ResponseWriter, r *http.Request) {
-	switch r.Host + r.URL.Path {
-	case "registry.npmjs.org/" + nonexistentPkg:
+	switch r.URL.Path {
+	case "/" + nonexistentPkg:
 		{
 			w.WriteHeader(404)
 			fmt.Fprint(w, `{"error":"Not found"}`)
 		}
-	case "registry.npmjs.org/" + unpopularPkg:
-	case "registry.npmjs.org/" + normalPkg:
+	case "/" + unpopularPkg:
+	case "/" + normalPkg:
 		{
 			fmt.Fprint(w, `{}`)
 		}
-	case "api.npmjs.org/downloads/point/last-month/" + unpopularPkg:
+	case "/downloads/point/last-month/" + unpopularPkg:
 		{
 			fmt.Fprintf(w, `{"downloads":3,"start":"2020-05-28","end":"2020-06-26","package":"%s"}`, unpopularPkg)
 		}
-	case "api.npmjs.org/downloads/point/last-month/" + normalPkg:
+	case "/downloads/point/last-month/" + normalPkg:
 		{
 			fmt.Fprintf(w, `{"downloads":31789789,"start":"2020-05-28","end":"2020-06-26","package":"%s"}`, normalPkg)
 		}
-	case "api.github.com/repos/" + unpopularRepo:
+	case "/repos/" + unpopularRepo:
 		{
 			fmt.Fprintf(w, `{"stargazers_count": 123}`)
 		}
-	case "api.github.com/repos/" + popularRepo:
+	case "/repos/" + popularRepo:
 		{
 			fmt.Fprintf(w, `{"stargazers_count": 500}`)
 		}
 	default:
-		panic(fmt.Sprintf("unknown path: %s", r.Host+r.URL.Path))
+		panic(fmt.Sprintf("unknown path: %s", r.URL.Path))
 	}
 }
 
@@ -67,7 +67,7 @@ func TestCheckerLint(t *testing.T) {
 	fakeBotPath := createFakeBotPath()
 	defer os.RemoveAll(fakeBotPath)
 	pckgPathRegex := "^packages/([a-z0-9])/([a-zA-Z0-9._-]+).json$"
-	httpTestProxy := "localhost:8667"
+	httpTestAddr := "localhost:8667"
 	file := path.Join(fakeBotPath, "packages", "packages", "i", "input-lint.json")
 
 	var (
@@ -409,13 +409,13 @@ func TestCheckerLint(t *testing.T) {
 		},
 	}
 
-	testproxy := &http.Server{
-		Addr:    httpTestProxy,
+	testServer := &http.Server{
+		Addr:    httpTestAddr,
 		Handler: http.Handler(http.HandlerFunc(fakeNpmGitHubHandlerLint)),
 	}
 
 	go func() {
-		if err := testproxy.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := testServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			panic(err)
 		}
 	}()
@@ -433,7 +433,7 @@ func TestCheckerLint(t *testing.T) {
 				assert.Nil(t, err)
 			}
 
-			out := runChecker(fakeBotPath, httpTestProxy, tc.validatePath, "lint", pkgFile)
+			out := runChecker(fakeBotPath, httpTestAddr, tc.validatePath, "lint", pkgFile)
 			for _, text := range tc.expected {
 				assert.Contains(t, out, strings.ReplaceAll(text, "\n", ""))
 			}
@@ -442,5 +442,5 @@ func TestCheckerLint(t *testing.T) {
 		})
 	}
 
-	assert.Nil(t, testproxy.Shutdown(context.Background()))
+	assert.Nil(t, testServer.Shutdown(context.Background()))
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MatchTestCase struct {
+	name     string
+	target   string
+	files    string
+	version  string
+	limits   string
+	expected []string
+}
+
+func matchInput(target, files, limits string) string {
+	if limits != "" {
+		limits = `"limits": ` + limits + `,`
+	}
+	return `{
+		"name": "a-happy-tyler",
+		"description": "Tyler is happy. Be like Tyler.",
+		"keywords": [
+			"tyler",
+			"happy"
+		],
+		"authors": [
+			{
+				"name": "Tyler Caslin",
+				"email": "tylercaslin47@gmail.com",
+				"url": "https://github.com/tc80"
+			}
+		],
+		"license": "MIT",
+		"repository": {
+			"type": "git",
+			"url": "git://github.com/tc80/a-happy-tyler.git"
+		},
+		"filename": "b.js",
+		"homepage": "https://github.com/tc80",` + limits + `
+		"autoupdate": {
+			"source": "npm",
+			"target": "` + target + `",
+			"fileMap": [
+				{ "basePath":"", "files":[` + files + `] }
+			]
+		}
+	}`
+}
+
+func TestCheckerMatch(t *testing.T) {
+	fakeBotPath := createFakeBotPath()
+	defer os.RemoveAll(fakeBotPath)
+
+	httpTestAddr := "localhost:8667"
+	file := path.Join(fakeBotPath, "packages", "packages", "i", "input-match.json")
+
+	cases := []MatchTestCase{
+		{
+			name:   "match files on npm",
+			target: jsFilesPkg,
+			files:  `"*.js"`,
+			expected: []string{`
+version: 0.0.2
+
+` + "```" + `
+a.js
+b.js
+` + "```" + `
+`},
+		},
+		{
+			name:   "unmatched pattern",
+			target: jsFilesPkg,
+			files:  `"b.js", "*.css"`,
+			expected: []string{
+				ciWarn(file, "pattern `*.css` did not match any file"),
+				"```\nb.js\n```\n",
+			},
+		},
+		{
+			name:   "oversized file",
+			target: oversizedFilesPkg,
+			files:  `"*.js"`,
+			expected: []string{
+				ciWarn(file, "file a.js ignored due to byte size (26214500 > 26214400)"),
+				"```\nb.js\n```\n",
+			},
+		},
+		{
+			name:    "specific version",
+			target:  sortByTimeStampPkg,
+			files:   `"*.js"`,
+			version: "1.0.0",
+			expected: []string{
+				"version: 1.0.0\n\n```\n1.js\n```\n",
+				ciError(file, "Filename `b.js` not found in version `1.0.0`.%0A"),
+			},
+		},
+		{
+			name:   "limits",
+			target: jsFilesPkg,
+			files:  `"*.js"`,
+			expected: []string{
+				"files: 2 (limit 50000)\ntotal size: 2 bytes (limit 209715200 bytes)\n",
+				"largest files:\n- a.js: 1 bytes\n- b.js: 1 bytes\n",
+			},
+		},
+		{
+			name:   "limits exceeded",
+			target: jsFilesPkg,
+			files:  `"*.js"`,
+			limits: `{ "maxFiles": 1, "maxSize": 1 }`,
+			expected: []string{
+				"files: 2 (limit 1)\ntotal size: 2 bytes (limit 1 bytes)\n",
+				"file count limit exceeded by 1 file(s)\nsize limit exceeded by 1 bytes\n",
+				ciError(file, "version exceeds the package limits, nothing will be published"),
+			},
+		},
+		{
+			name:    "unknown version",
+			target:  jsFilesPkg,
+			files:   `"*.js"`,
+			version: "9.9.9",
+			expected: []string{
+				ciError(file, "version `9.9.9` not found on npm"),
+			},
+		},
+		{
+			name:   "files outside the package",
+			target: walkerPkg,
+			files:  `"**/*.js"`,
+			expected: []string{
+				ciWarn(file, "Unsafe file ignored (located outside of the archive) with name: `package/../../b.js`"),
+				ciWarn(file, "Unsafe file ignored (located outside of the archive) with name: `package/../../../c.js`"),
+				"```\na.js\n```\n",
+			},
+		},
+		{
+			name:   "corrupted tarball",
+			target: corruptedPkg,
+			files:  `"*.js"`,
+			expected: []string{
+				"could not extract version",
+				"expected 0000000000000000000000000000000000000000",
+			},
+		},
+	}
+
+	testServer := &http.Server{
+		Addr:    httpTestAddr,
+		Handler: http.Handler(http.HandlerFunc(fakeNpmHandlerShowFiles)),
+	}
+
+	go func() {
+		if err := testServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			panic(err)
+		}
+	}()
+
+	for _, tc := range cases {
+		tc := tc // capture range variable
+
+		// since all tests share the same input, this needs to run sequentially
+		t.Run(tc.name, func(t *testing.T) {
+			err := ioutil.WriteFile(file, []byte(matchInput(tc.target, tc.files, tc.limits)), 0644)
+			assert.Nil(t, err)
+			defer os.Remove(file)
+
+			args := []string{"match", file}
+			if tc.version != "" {
+				args = append(args, tc.version)
+			}
+			out := runChecker(fakeBotPath, httpTestAddr, false, args...)
+			for _, text := range tc.expected {
+				assert.Contains(t, out, text)
+			}
+		})
+	}
+
+	assert.Nil(t, testServer.Shutdown(context.Background()))
+}
@@ -106,6 +106,10 @@ func TestHumanReadableSchema(t *testing.T) {
 			filePath: "schema_tests/human_schema_tests/autoupdate/valid/source_npm.json",
 			valid:    true,
 		},
+		{
+			filePath: "schema_tests/human_schema_tests/autoupdate/valid/source_github_release.json",
+			valid:    true,
+		},
 		// autoupdate invalid
 		{
 			filePath: "schema_tests/human_schema_tests/autoupdate/invalid/additional_properties.json",
@@ -170,6 +174,10 @@ func TestHumanReadableSchema(t *testing.T) {
 			filePath: "schema_tests/human_schema_tests/autoupdate/invalid/source_svn.json",
 			errors:   []string{"autoupdate.source: Does not match pattern '" + autoupdateSourceRegex + "'"},
 		},
+		{
+			filePath: "schema_tests/human_schema_tests/autoupdate/invalid/source_gitlab.json",
+			errors:   []string{"autoupdate.source: Does not match pattern '" + autoupdateSourceRegex + "'"},
+		},
 		// description valid
 		{
 			filePath: "schema_tests/human_schema_tests/description/valid/valid_description.json",
@@ -356,6 +364,10 @@ func TestHumanReadableSchema(t *testing.T) {
 			filePath: "schema_tests/human_schema_tests/optimization/valid/no_optimization.json",
 			valid:    true,
 		},
+		{
+			filePath: "schema_tests/human_schema_tests/optimization/valid/minifier.json",
+			valid:    true,
+		},
 		// optimization invalid
 		{
 			filePath: "schema_tests/human_schema_tests/optimization/invalid/invalid_key.json",
@@ -365,6 +377,36 @@ func TestHumanReadableSchema(t *testing.T) {
 			filePath: "schema_tests/human_schema_tests/optimization/invalid/not_boolean.json",
 			errors:   []string{"optimization.js: Invalid type. Expected: boolean, given: string"},
 		},
+		{
+			filePath: "schema_tests/human_schema_tests/optimization/invalid/unknown_minifier.json",
+			errors:   []string{"optimization.minifier.name: optimization.minifier.name must be one of the following: \"uglify\", \"terser\", \"esbuild\""},
+		},
+		{
+			filePath: "schema_tests/human_schema_tests/optimization/invalid/invalid_target.json",
+			errors:   []string{"optimization.minifier.target: Does not match pattern '^es(5|20[0-9]{2}|next)$'"},
+		},
+		// limits valid
+		{
+			filePath: "schema_tests/human_schema_tests/limits/valid/all_fields.json",
+			valid:    true,
+		},
+		{
+			filePath: "schema_tests/human_schema_tests/limits/valid/max_files_only.json",
+			valid:    true,
+		},
+		// limits invalid
+		{
+			filePath: "schema_tests/human_schema_tests/limits/invalid/invalid_key.json",
+			errors:   []string{"limits: Additional property happy is not allowed"},
+		},
+		{
+			filePath: "schema_tests/human_schema_tests/limits/invalid/not_integer.json",
+			errors:   []string{"limits.maxSize: Invalid type. Expected: integer, given: string"},
+		},
+		{
+			filePath: "schema_tests/human_schema_tests/limits/invalid/zero.json",
+			errors:   []string{"limits.maxFiles: Must be greater than or equal to 1"},
+		},
 	}
 
 	runSchemaTestCases(t, packages.HumanReadableSchema, cases)
@@ -32,6 +32,20 @@ func TestNonHumanReadableSchema(t *testing.T) {
 			filePath: "schema_tests/non_human_schema_tests/repository/valid/missing_repository.json",
 			valid:    true,
 		},
+		// rename valid
+		{
+			filePath: "schema_tests/non_human_schema_tests/rename/valid/renamed_to.json",
+			valid:    true,
+		},
+		{
+			filePath: "schema_tests/non_human_schema_tests/rename/valid/former_names.json",
+			valid:    true,
+		},
+		// rename invalid
+		{
+			filePath: "schema_tests/non_human_schema_tests/rename/invalid/invalid_renamed_to.json",
+			errors:   []string{"renamedTo: Does not match pattern '^[a-zA-Z0-9._-]+$'"},
+		},
 		// version valid
 		{
 			filePath: "schema_tests/non_human_schema_tests/version/valid/valid_version.json",
@@ -65,7 +65,7 @@ func TestCheckerShowFilesGitSymlink(t *testing.T) {
 	})
 	defer os.RemoveAll(symbolicGit)
 
-	httpTestProxy := "localhost:8666"
+	httpTestAddr := "localhost:8666"
 	pkgFile := path.Join(fakeBotPath, "packages", "packages", "i", "input-show-files.json")
 	input := `{
 		"name": "a-happy-tyler",
@@ -107,20 +107,20 @@ c.js
 	assert.Nil(t, err)
 	defer os.Remove(pkgFile)
 
-	testproxy := &http.Server{
-		Addr:    httpTestProxy,
+	testServer := &http.Server{
+		Addr:    httpTestAddr,
 		Handler: http.Handler(http.HandlerFunc(fakeNpmHandlerShowFiles)),
 	}
 
 	go func() {
-		if err := testproxy.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := testServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			panic(err)
 		}
 	}()
 
 	// TODO: mock sandbox
 	_ = expected
-	// out := runChecker(fakeBotPath, httpTestProxy, false, "show-files", pkgFile)
+	// out := runChecker(fakeBotPath, httpTestAddr, false, "show-files", pkgFile)
 	// assert.Contains(t, out, expected)
-	assert.Nil(t, testproxy.Shutdown(context.Background()))
+	assert.Nil(t, testServer.Shutdown(context.Background()))
 }
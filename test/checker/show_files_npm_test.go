@@ -24,6 +24,7 @@ const (
 	sortByTimeStampPkg  = "sortByTimePkg"
 	symlinkPkg          = "symlinkPkg"
 	walkerPkg           = "walkerPkg"
+	corruptedPkg        = "corruptedPkg"
 	timeStamp1          = "1.0.0"
 	timeStamp2          = "2.0.0"
 	timeStamp3          = "3.0.0"
@@ -121,7 +122,7 @@ func fakeNpmHandlerShowFiles(w http.ResponseWriter, r *http.Request) {
 			"versions": {
 				"0.0.2": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+jsFilesPkg+`.tgz"
+						"tarball": "http://`+r.Host+`/`+jsFilesPkg+`.tgz"
 					}
 				}
 			},
@@ -135,7 +136,7 @@ func fakeNpmHandlerShowFiles(w http.ResponseWriter, r *http.Request) {
 			"versions": {
 				"0.0.2": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+oversizedFilesPkg+`.tgz"
+						"tarball": "http://`+r.Host+`/`+oversizedFilesPkg+`.tgz"
 					}
 				}
 			},
@@ -149,7 +150,7 @@ func fakeNpmHandlerShowFiles(w http.ResponseWriter, r *http.Request) {
 			"versions": {
 				"1.3.1": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+unpublishedFieldPkg+`.tgz"
+						"tarball": "http://`+r.Host+`/`+unpublishedFieldPkg+`.tgz"
 					}
 				}
 			},
@@ -174,27 +175,27 @@ func fakeNpmHandlerShowFiles(w http.ResponseWriter, r *http.Request) {
 			"versions": {
 				"1.0.0": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+timeStamp1+`.tgz"
+						"tarball": "http://`+r.Host+`/`+timeStamp1+`.tgz"
 					}
 				},
 				"2.0.0": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+timeStamp2+`.tgz"
+						"tarball": "http://`+r.Host+`/`+timeStamp2+`.tgz"
 					}
 				},
 				"3.0.0": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+timeStamp3+`.tgz"
+						"tarball": "http://`+r.Host+`/`+timeStamp3+`.tgz"
 					}
 				},
 				"4.0.0": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+timeStamp4+`.tgz"
+						"tarball": "http://`+r.Host+`/`+timeStamp4+`.tgz"
 					}
 				},
 				"5.0.0": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+timeStamp5+`.tgz"
+						"tarball": "http://`+r.Host+`/`+timeStamp5+`.tgz"
 					}
 				}
 			},
@@ -214,7 +215,7 @@ func fakeNpmHandlerShowFiles(w http.ResponseWriter, r *http.Request) {
 			"versions": {
 				"0.0.2": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+symlinkPkg+`.tgz"
+						"tarball": "http://`+r.Host+`/`+symlinkPkg+`.tgz"
 					}
 				}
 			},
@@ -228,7 +229,22 @@ func fakeNpmHandlerShowFiles(w http.ResponseWriter, r *http.Request) {
 			"versions": {
 				"0.0.2": {
 					"dist": {
-						"tarball": "http://registry.npmjs.org/`+walkerPkg+`.tgz"
+						"tarball": "http://`+r.Host+`/`+walkerPkg+`.tgz"
+					}
+				}
+			},
+			"time": { "0.0.2": "2012-06-19T04:01:32.220Z" },
+			"dist-tags": {
+				"latest": "0.0.2"
+			}
+		}`)
+	case "/" + corruptedPkg:
+		fmt.Fprint(w, `{
+			"versions": {
+				"0.0.2": {
+					"dist": {
+						"tarball": "http://`+r.Host+`/`+jsFilesPkg+`.tgz",
+						"shasum": "0000000000000000000000000000000000000000"
 					}
 				}
 			},
@@ -294,7 +310,7 @@ func TestCheckerNPMShowFiles(t *testing.T) {
 	fakeBotPath := createFakeBotPath()
 	defer os.RemoveAll(fakeBotPath)
 
-	httpTestProxy := "localhost:8666"
+	httpTestAddr := "localhost:8666"
 	file := path.Join(fakeBotPath, "packages", "packages", "i", "input-show-files.json")
 
 	cases := []ShowFilesTestCase{
@@ -522,13 +538,13 @@ most recent version: 2.0.0
 		},
 	}
 
-	testproxy := &http.Server{
-		Addr:    httpTestProxy,
+	testServer := &http.Server{
+		Addr:    httpTestAddr,
 		Handler: http.Handler(http.HandlerFunc(fakeNpmHandlerShowFiles)),
 	}
 
 	go func() {
-		if err := testproxy.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := testServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			panic(err)
 		}
 	}()
@@ -550,21 +566,21 @@ most recent version: 2.0.0
 			_ = tc.expected
 			_ = tc.validatePath
 			//
-			// out := runChecker(fakeBotPath, httpTestProxy, tc.validatePath, "show-files", pkgFile)
+			// out := runChecker(fakeBotPath, httpTestAddr, tc.validatePath, "show-files", pkgFile)
 			// assert.Equal(t, tc.expected, "\n"+out)
 
 			os.Remove(pkgFile)
 		})
 	}
 
-	assert.Nil(t, testproxy.Shutdown(context.Background()))
+	assert.Nil(t, testServer.Shutdown(context.Background()))
 }
 
 func TestCheckerShowFilesNPMSymlink(t *testing.T) {
 	fakeBotPath := createFakeBotPath()
 	defer os.RemoveAll(fakeBotPath)
 
-	httpTestProxy := "localhost:8666"
+	httpTestAddr := "localhost:8666"
 	pkgFile := path.Join(fakeBotPath, "packages", "packages", "i", "input-show-files.json")
 	input := `{
 		"name": "a-happy-tyler",
@@ -605,13 +621,13 @@ c.js
 	assert.Nil(t, err)
 	defer os.Remove(pkgFile)
 
-	testproxy := &http.Server{
-		Addr:    httpTestProxy,
+	testServer := &http.Server{
+		Addr:    httpTestAddr,
 		Handler: http.Handler(http.HandlerFunc(fakeNpmHandlerShowFiles)),
 	}
 
 	go func() {
-		if err := testproxy.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := testServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			panic(err)
 		}
 	}()
@@ -619,16 +635,16 @@ c.js
 	// TODO: mock sandbox
 	_ = expected
 	//
-	// out := runChecker(fakeBotPath, httpTestProxy, false, "show-files", pkgFile)
+	// out := runChecker(fakeBotPath, httpTestAddr, false, "show-files", pkgFile)
 	// assert.Contains(t, out, expected)
-	assert.Nil(t, testproxy.Shutdown(context.Background()))
+	assert.Nil(t, testServer.Shutdown(context.Background()))
 }
 
 func TestCheckerShowFilesTarWalker(t *testing.T) {
 	fakeBotPath := createFakeBotPath()
 	defer os.RemoveAll(fakeBotPath)
 
-	httpTestProxy := "localhost:8666"
+	httpTestAddr := "localhost:8666"
 	pkgFile := path.Join(fakeBotPath, "packages", "packages", "i", "input-show-files.json")
 	input := `{
 		"name": "a-happy-tyler",
@@ -671,22 +687,22 @@ a.js
 	assert.Nil(t, err)
 	defer os.Remove(pkgFile)
 
-	testproxy := &http.Server{
-		Addr:    httpTestProxy,
+	testServer := &http.Server{
+		Addr:    httpTestAddr,
 		Handler: http.Handler(http.HandlerFunc(fakeNpmHandlerShowFiles)),
 	}
 
 	go func() {
-		if err := testproxy.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := testServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			panic(err)
 		}
 	}()
 
 	// TODO: mock sandbox
 	_ = expected
-	// out := runChecker(fakeBotPath, httpTestProxy, false, "show-files", pkgFile)
+	// out := runChecker(fakeBotPath, httpTestAddr, false, "show-files", pkgFile)
 	// for _, text := range expected {
 	// 	assert.Contains(t, out, text)
 	// }
-	assert.Nil(t, testproxy.Shutdown(context.Background()))
+	assert.Nil(t, testServer.Shutdown(context.Background()))
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/compress"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unZstd(compressed []byte) []byte {
+	var out bytes.Buffer
+	if err := compress.UnZstd(&out, bytes.NewReader(compressed)); err != nil {
+		panic(err)
+	}
+	return out.Bytes()
+}
+
+var content = []byte(strings.Repeat("function hello(name) { return 'hello ' + name; }\n", 200))
+
+func TestEncodersRoundTrip(t *testing.T) {
+	encoders := []struct {
+		encoder compress.Encoder
+		decode  func([]byte) []byte
+	}{
+		{compress.Brotli{Quality: compress.DefaultBrotliQuality}, compress.UnBrotli},
+		{compress.Brotli{Quality: 0}, compress.UnBrotli},
+		{compress.Gzip{Level: compress.DefaultGzipLevel}, compress.UnGzip},
+		{compress.Gzip{Level: 1}, compress.UnGzip},
+		{compress.Zstd{Level: compress.DefaultZstdLevel}, unZstd},
+		{compress.Zstd{Level: 1}, unZstd},
+	}
+
+	for _, tc := range encoders {
+		var out bytes.Buffer
+		assert.Nil(t, tc.encoder.Encode(&out, bytes.NewReader(content)))
+		assert.Less(t, out.Len(), len(content))
+		assert.Equal(t, content, tc.decode(out.Bytes()))
+	}
+}
+
+func TestEncodersDeterministic(t *testing.T) {
+	for _, e := range []compress.Encoder{compress.Brotli{Quality: 11}, compress.Gzip{Level: 9}, compress.Zstd{Level: 19}} {
+		var a, b bytes.Buffer
+		assert.Nil(t, e.Encode(&a, bytes.NewReader(content)))
+		assert.Nil(t, e.Encode(&b, bytes.NewReader(content)))
+		assert.Equal(t, a.Bytes(), b.Bytes(), e.Ext())
+	}
+}
+
+func TestInvalidLevel(t *testing.T) {
+	var out bytes.Buffer
+	assert.NotNil(t, compress.Gzip{Level: 42}.Encode(&out, bytes.NewReader(content)))
+	assert.NotNil(t, compress.Zstd{Level: 22}.Encode(&out, bytes.NewReader(content)))
+}
+
+func TestEncodersFromEnv(t *testing.T) {
+	defer os.Unsetenv("BROTLI_QUALITY")
+	defer os.Unsetenv("GZIP_LEVEL")
+	defer os.Unsetenv("ZSTD_LEVEL")
+
+	assert.Equal(t, []compress.Encoder{
+		compress.Brotli{Quality: 11},
+		compress.Gzip{Level: 9},
+		compress.Zstd{Level: 19},
+	}, compress.Encoders())
+
+	os.Setenv("BROTLI_QUALITY", "5")
+	os.Setenv("GZIP_LEVEL", "12")
+	os.Setenv("ZSTD_LEVEL", "3")
+	assert.Equal(t, []compress.Encoder{
+		compress.Brotli{Quality: 5},
+		compress.Gzip{Level: 9},
+		compress.Zstd{Level: 3},
+	}, compress.Encoders())
+}
+
+func TestEncodeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compress")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "a.js")
+	assert.Nil(t, ioutil.WriteFile(src, content, 0644))
+
+	for _, e := range compress.Encoders() {
+		dest := src + e.Ext()
+		assert.Nil(t, compress.EncodeFile(e, src, dest))
+
+		var expected bytes.Buffer
+		assert.Nil(t, e.Encode(&expected, bytes.NewReader(content)))
+
+		actual, err := ioutil.ReadFile(dest)
+		assert.Nil(t, err)
+		assert.Equal(t, expected.Bytes(), actual, e.Ext())
+	}
+
+	assert.NotNil(t, compress.EncodeFile(compress.Gzip{Level: 9}, path.Join(dir, "missing.js"), path.Join(dir, "missing.js.gz")))
+}
+
+func TestDecode(t *testing.T) {
+	for _, e := range compress.Encoders() {
+		var compressed, out bytes.Buffer
+		assert.Nil(t, e.Encode(&compressed, bytes.NewReader(content)))
+		assert.Nil(t, compress.Decode(e.Ext(), &out, &compressed), e.Ext())
+		assert.Equal(t, content, out.Bytes(), e.Ext())
+	}
+
+	var out bytes.Buffer
+	assert.NotNil(t, compress.Decode(".zst", &out, bytes.NewReader(content)))
+	assert.NotNil(t, compress.Decode(".woff2", &out, bytes.NewReader(content)))
+}
+
+func TestTrimExt(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"a.js.br", "a.js", true},
+		{"a.js.gz", "a.js", true},
+		{"dist/a.gz.js.zst", "dist/a.gz.js", true},
+		{"a.woff2", "a.woff2", false},
+		{"a.js.sri", "a.js.sri", false},
+	}
+
+	for _, tc := range cases {
+		name, ok := compress.TrimExt(tc.name)
+		assert.Equal(t, tc.ok, ok, tc.name)
+		assert.Equal(t, tc.expected, name, tc.name)
+	}
+}
+
+func TestIsCompressedFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compress")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var gz bytes.Buffer
+	assert.Nil(t, compress.Gzip{Level: 9}.Encode(&gz, bytes.NewReader(content)))
+
+	cases := []struct {
+		name       string
+		content    []byte
+		compressed bool
+	}{
+		{"a.js", content, false},
+		{"empty.js", []byte{}, false},
+		{"a.png", []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR"), true},
+		{"a.jpg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF"), true},
+		{"a.woff", []byte("wOFF\x00\x01\x00\x00"), true},
+		{"a.zip", []byte("PK\x03\x04\x14\x00"), true},
+		{"a.mp4", []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), true},
+		{"a.tgz", gz.Bytes(), true},
+	}
+
+	for _, tc := range cases {
+		src := path.Join(dir, tc.name)
+		assert.Nil(t, ioutil.WriteFile(src, tc.content, 0644))
+
+		compressed, err := compress.IsCompressedFormat(src)
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, tc.compressed, compressed, tc.name)
+	}
+
+	_, err = compress.IsCompressedFormat(path.Join(dir, "missing.js"))
+	assert.NotNil(t, err)
+}
+
+func TestEncodeFilesIncompressible(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compress")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	encoders := compress.Encoders()
+
+	// compressible file
+	src := path.Join(dir, "a.js")
+	assert.Nil(t, ioutil.WriteFile(src, content, 0644))
+	smaller, err := compress.EncodeFiles(encoders, src, path.Join(dir, "out.js"))
+	assert.Nil(t, err)
+	assert.True(t, smaller)
+	for _, e := range encoders {
+		_, err := os.Stat(path.Join(dir, "out.js"+e.Ext()))
+		assert.Nil(t, err, e.Ext())
+	}
+
+	// tiny files grow once compressed
+	src = path.Join(dir, "b.js")
+	assert.Nil(t, ioutil.WriteFile(src, []byte("a"), 0644))
+	smaller, err = compress.EncodeFiles(encoders, src, path.Join(dir, "out-b.js"))
+	assert.Nil(t, err)
+	assert.False(t, smaller)
+	for _, e := range encoders {
+		_, err := os.Stat(path.Join(dir, "out-b.js"+e.Ext()))
+		assert.True(t, os.IsNotExist(err), e.Ext())
+	}
+}
+
+func TestOriginalName(t *testing.T) {
+	raw := map[string]bool{"x.tar.gz": true}
+
+	name, compressed := compress.OriginalName("x.tar.gz", raw)
+	assert.Equal(t, "x.tar.gz", name)
+	assert.False(t, compressed)
+
+	name, compressed = compress.OriginalName("a.js.gz", raw)
+	assert.Equal(t, "a.js", name)
+	assert.True(t, compressed)
+}
+
+func TestRawManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raw")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	raw, err := compress.ReadRawManifest(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, raw)
+
+	assert.Nil(t, compress.WriteRawManifest(dir, []string{"x.tar.gz", "notes.raw"}))
+	raw, err = compress.ReadRawManifest(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"notes.raw": true, "x.tar.gz": true}, raw)
+
+	_, err = compress.ParseRawManifest(strings.NewReader("{"))
+	assert.NotNil(t, err)
+}
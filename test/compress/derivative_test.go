@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/compress"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A fake image encoder writing its arguments to the last one, or
+// failing if FAKE_ENCODER_FAIL is set.
+const fakeEncoder = `#!/bin/sh
+if [ -n "$FAKE_ENCODER_FAIL" ]; then echo "%[1]s: corrupt image" >&2; exit 1; fi
+for out in "$@"; do :; done
+echo "%[1]s $*" > "$out"
+`
+
+func createFakeEncoders(t *testing.T, dir string) func() {
+	restore := createFakeBinaries(t, dir, fakeEncoder, map[string]*string{
+		"cwebp":   &compress.CWEBP,
+		"avifenc": &compress.AVIFENC,
+	})
+	return func() {
+		restore()
+		os.Unsetenv("FAKE_ENCODER_FAIL")
+	}
+}
+
+func TestDerivative(t *testing.T) {
+	dir, err := ioutil.TempDir("", "derivative")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer createFakeEncoders(t, dir)()
+
+	// large enough for the fake derivatives to be smaller
+	image := make([]byte, 4096)
+	png := path.Join(dir, "logo.png")
+	jpg := path.Join(dir, "photo.jpg")
+	assert.Nil(t, ioutil.WriteFile(png, image, 0644))
+	assert.Nil(t, ioutil.WriteFile(jpg, image, 0644))
+
+	cases := []struct {
+		file     string
+		format   compress.ImageFormat
+		expected string
+	}{
+		{png, compress.WebP, "cwebp -quiet -metadata icc -lossless {src} -o {src}.webp"},
+		{jpg, compress.WebP, "cwebp -quiet -metadata icc -q 80 {src} -o {src}.webp"},
+		{png, compress.AVIF, "avifenc --jobs 1 --speed 6 --lossless {src} {src}.avif"},
+		{jpg, compress.AVIF, "avifenc --jobs 1 --speed 6 {src} {src}.avif"},
+	}
+
+	for _, tc := range cases {
+		out, err := compress.Derivative(context.Background(), tc.file, tc.format)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.file+tc.format.Ext, *out)
+
+		content, err := ioutil.ReadFile(*out)
+		assert.Nil(t, err)
+		assert.Equal(t, strings.ReplaceAll(tc.expected, "{src}", tc.file)+"\n", string(content))
+
+		// the derivative already exists
+		out, err = compress.Derivative(context.Background(), tc.file, tc.format)
+		assert.Nil(t, err)
+		assert.Nil(t, out)
+	}
+}
+
+func TestDerivativeNotGenerated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "derivative")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer createFakeEncoders(t, dir)()
+
+	// the derivative is larger than the original
+	tiny := path.Join(dir, "tiny.png")
+	assert.Nil(t, ioutil.WriteFile(tiny, []byte("png"), 0644))
+	out, err := compress.Derivative(context.Background(), tiny, compress.WebP)
+	assert.Nil(t, err)
+	assert.Nil(t, out)
+	_, err = os.Stat(tiny + ".webp")
+	assert.True(t, os.IsNotExist(err))
+
+	// the encoder fails
+	image := path.Join(dir, "corrupt.png")
+	assert.Nil(t, ioutil.WriteFile(image, make([]byte, 4096), 0644))
+	os.Setenv("FAKE_ENCODER_FAIL", "1")
+	out, err = compress.Derivative(context.Background(), image, compress.AVIF)
+	assert.Nil(t, out)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "could not generate .avif derivative of corrupt.png")
+	assert.Contains(t, err.Error(), "avifenc: corrupt image")
+
+	// the encoder is not installed
+	compress.CWEBP = path.Join(dir, "missing")
+	out, err = compress.Derivative(context.Background(), image, compress.WebP)
+	assert.Nil(t, err)
+	assert.Nil(t, out)
+}
+
+func TestDerivativeInstalled(t *testing.T) {
+	skipIfMissing(t, compress.CWEBP, compress.AVIFENC)
+
+	dir, err := ioutil.TempDir("", "derivative")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for x := 0; x < 256; x++ {
+		for y := 0; y < 256; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	pngFile := path.Join(dir, "logo.png")
+	jpgFile := path.Join(dir, "photo.jpg")
+	f, err := os.Create(pngFile)
+	assert.Nil(t, err)
+	assert.Nil(t, png.Encode(f, img))
+	assert.Nil(t, f.Close())
+	f, err = os.Create(jpgFile)
+	assert.Nil(t, err)
+	assert.Nil(t, jpeg.Encode(f, img, &jpeg.Options{Quality: 95}))
+	assert.Nil(t, f.Close())
+
+	for _, file := range []string{pngFile, jpgFile} {
+		for _, format := range []compress.ImageFormat{compress.WebP, compress.AVIF} {
+			out, err := compress.Derivative(context.Background(), file, format)
+			assert.Nil(t, err, file+format.Ext)
+			if out == nil {
+				// not smaller than the original
+				continue
+			}
+
+			original, err := os.Stat(file)
+			assert.Nil(t, err)
+			derivative, err := os.Stat(*out)
+			assert.Nil(t, err)
+			assert.Greater(t, derivative.Size(), int64(0), *out)
+			assert.Less(t, derivative.Size(), original.Size(), *out)
+		}
+	}
+}
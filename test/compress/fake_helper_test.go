@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Replaces the binaries by fake shell scripts in dir, formatted with
+// the name of each binary. Returns a function restoring the binaries.
+func createFakeBinaries(t *testing.T, dir, script string, bins map[string]*string) func() {
+	old := make(map[string]string, len(bins))
+	for name, bin := range bins {
+		old[name] = *bin
+		fake := path.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(fake, []byte(fmt.Sprintf(script, name)), 0755))
+		*bin = fake
+	}
+
+	return func() {
+		for name, bin := range bins {
+			*bin = old[name]
+		}
+	}
+}
+
+// Skips the test if one of the binaries is not installed.
+func skipIfMissing(t *testing.T, bins ...string) {
+	for _, bin := range bins {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is not installed", bin)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/compress"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A fake minifier recording its arguments in the output, or
+// failing if its name is in the FAKE_MINIFIER_FAIL variable.
+// Its source map references the source by its absolute path.
+const fakeMinifier = `#!/bin/sh
+case "$FAKE_MINIFIER_FAIL" in
+  *%[1]s*) echo "%[1]s: unexpected token" >&2; exit 1;;
+esac
+prev=""
+for arg in "$@"; do
+  if [ "$prev" = "-o" ]; then
+    out="$arg"
+  else
+    case "$arg" in
+      --outfile=*) out="${arg#--outfile=}";;
+      --source-map|--sourcemap) map=1;;
+      /*.js|/*.css) src="$arg";;
+    esac
+  fi
+  prev="$arg"
+done
+echo "%[1]s $*" > "$out"
+if [ -n "$map" ]; then
+  echo "{\"version\":3,\"sources\":[\"$src\"],\"names\":[],\"mappings\":\"AAAA\"}" > "$out.map"
+fi
+`
+
+func createFakeMinifiers(t *testing.T, dir string) func() {
+	restore := createFakeBinaries(t, dir, fakeMinifier, map[string]*string{
+		"uglify":   &compress.UGLIFYJS,
+		"terser":   &compress.TERSER,
+		"esbuild":  &compress.ESBUILD,
+		"cleancss": &compress.CLEANCSS,
+	})
+	return func() {
+		restore()
+		os.Unsetenv("FAKE_MINIFIER_FAIL")
+	}
+}
+
+// Returns the sources of a source map.
+func readSourceMapSources(t *testing.T, file string) []string {
+	content, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	var sourceMap struct {
+		Sources []string `json:"sources"`
+	}
+	assert.Nil(t, json.Unmarshal(content, &sourceMap))
+	return sourceMap.Sources
+}
+
+func getMinifiers(t *testing.T, names ...string) []compress.JsMinifier {
+	minifiers := make([]compress.JsMinifier, 0)
+	for _, name := range names {
+		m, err := compress.GetJsMinifier(name)
+		assert.Nil(t, err)
+		minifiers = append(minifiers, m)
+	}
+	return minifiers
+}
+
+func TestJsMinifiers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer createFakeMinifiers(t, dir)()
+
+	src := path.Join(dir, "lib.js")
+	out := path.Join(dir, "lib.min.js")
+
+	cases := []struct {
+		minifier string
+		opts     compress.MinifyOptions
+		expected string
+	}{
+		{"uglify", compress.MinifyOptions{Mangle: true, Compress: true},
+			"uglify --mangle --compress if_return=true -o {out} {src}"},
+		{"uglify", compress.MinifyOptions{SourceMap: true},
+			"uglify -o {out} {src} --source-map base='{dir}',url='lib.min.js.map',includeSources"},
+		{"terser", compress.MinifyOptions{Mangle: true, Compress: true, Target: "es2018"},
+			"terser {src} --mangle --compress --ecma 2018 -o {out}"},
+		{"terser", compress.MinifyOptions{Target: "esnext"},
+			"terser {src} -o {out}"},
+		{"esbuild", compress.MinifyOptions{Mangle: true, Compress: true, Target: "es2015", SourceMap: true},
+			"esbuild {src} --minify-whitespace --log-level=warning --minify-identifiers --minify-syntax --target=es2015 --outfile={out} --sourcemap --sources-content=true"},
+	}
+
+	for _, tc := range cases {
+		assert.Nil(t, ioutil.WriteFile(src, []byte("let a = 1;"), 0644))
+
+		res, err := compress.Js(context.Background(), src, getMinifiers(t, tc.minifier), tc.opts)
+		assert.Nil(t, err, tc.minifier)
+		assert.Equal(t, &out, res, tc.minifier)
+
+		content, err := ioutil.ReadFile(out)
+		assert.Nil(t, err)
+		expected := strings.NewReplacer("{src}", src, "{out}", out, "{dir}", dir).Replace(tc.expected)
+		assert.Equal(t, expected+"\n", string(content), tc.minifier)
+
+		assert.Nil(t, os.Remove(out))
+		os.Remove(out + ".map")
+	}
+}
+
+func TestJsSourceMaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer createFakeMinifiers(t, dir)()
+
+	src := path.Join(dir, "dist", "lib.js")
+	out := path.Join(dir, "dist", "lib.min.js")
+	assert.Nil(t, os.Mkdir(path.Join(dir, "dist"), 0755))
+
+	for _, name := range []string{"uglify", "terser", "esbuild"} {
+		assert.Nil(t, ioutil.WriteFile(src, []byte("let a = 1;"), 0644))
+
+		res, err := compress.Js(context.Background(), src, getMinifiers(t, name), compress.MinifyOptions{SourceMap: true})
+		assert.Nil(t, err, name)
+		assert.Equal(t, &out, res, name)
+
+		// the sources do not leak the workspace
+		assert.Equal(t, []string{"lib.js"}, readSourceMapSources(t, out+".map"), name)
+
+		assert.Nil(t, os.Remove(out))
+		assert.Nil(t, os.Remove(out+".map"))
+	}
+
+	// no source map requested
+	res, err := compress.Js(context.Background(), src, getMinifiers(t, "terser"), compress.MinifyOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, &out, res)
+	_, err = os.Stat(out + ".map")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCSSSourceMaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer createFakeMinifiers(t, dir)()
+
+	src := path.Join(dir, "style.css")
+	out := path.Join(dir, "style.min.css")
+	assert.Nil(t, ioutil.WriteFile(src, []byte("a { color: red; }"), 0644))
+
+	res, err := compress.CSS(context.Background(), src, true)
+	assert.Nil(t, err)
+	assert.Equal(t, &out, res)
+	assert.Equal(t, []string{"style.css"}, readSourceMapSources(t, out+".map"))
+
+	// a failed minification does not leave a source map
+	assert.Nil(t, os.Remove(out))
+	assert.Nil(t, os.Remove(out+".map"))
+	os.Setenv("FAKE_MINIFIER_FAIL", "cleancss")
+	res, err = compress.CSS(context.Background(), src, true)
+	assert.Nil(t, res)
+	assert.NotNil(t, err)
+	_, err = os.Stat(out + ".map")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestJsMinifiersInstalled(t *testing.T) {
+	skipIfMissing(t, compress.UGLIFYJS, compress.TERSER, compress.ESBUILD)
+
+	dir, err := ioutil.TempDir("", "minify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "lib.js")
+	out := path.Join(dir, "lib.min.js")
+	code := "function hello(name) {\n  var greeting = 'hello ' + name;\n  return greeting;\n}\n"
+	opts := compress.MinifyOptions{Mangle: true, Compress: true, SourceMap: true}
+
+	for _, name := range []string{"uglify", "terser", "esbuild"} {
+		assert.Nil(t, ioutil.WriteFile(src, []byte(code), 0644))
+
+		res, err := compress.Js(context.Background(), src, getMinifiers(t, name), opts)
+		assert.Nil(t, err, name)
+		assert.Equal(t, &out, res, name)
+
+		content, err := ioutil.ReadFile(out)
+		assert.Nil(t, err)
+		assert.Less(t, len(content), len(code), name)
+		assert.Equal(t, []string{"lib.js"}, readSourceMapSources(t, out+".map"), name)
+
+		assert.Nil(t, os.Remove(out))
+		assert.Nil(t, os.Remove(out+".map"))
+	}
+}
+
+func TestCSSInstalled(t *testing.T) {
+	skipIfMissing(t, compress.CLEANCSS)
+
+	dir, err := ioutil.TempDir("", "minify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "style.css")
+	out := path.Join(dir, "style.min.css")
+	code := "a {\n  color: #ff0000;\n}\n"
+	assert.Nil(t, ioutil.WriteFile(src, []byte(code), 0644))
+
+	res, err := compress.CSS(context.Background(), src, true)
+	assert.Nil(t, err)
+	assert.Equal(t, &out, res)
+
+	content, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Less(t, len(content), len(code))
+	assert.Equal(t, []string{"style.css"}, readSourceMapSources(t, out+".map"))
+}
+
+func TestJsMinifierFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer createFakeMinifiers(t, dir)()
+
+	src := path.Join(dir, "lib.js")
+	out := path.Join(dir, "lib.min.js")
+	assert.Nil(t, ioutil.WriteFile(src, []byte("let a = 1;"), 0644))
+
+	// uglify fails on modern syntax, terser is tried next
+	os.Setenv("FAKE_MINIFIER_FAIL", "uglify")
+	res, err := compress.Js(context.Background(), src, getMinifiers(t, compress.DefaultJsMinifiers...), compress.MinifyOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, &out, res)
+	content, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "terser "))
+	assert.Nil(t, os.Remove(out))
+
+	// all minifiers fail
+	os.Setenv("FAKE_MINIFIER_FAIL", "uglify,terser")
+	res, err = compress.Js(context.Background(), src, getMinifiers(t, compress.DefaultJsMinifiers...), compress.MinifyOptions{})
+	assert.Nil(t, res)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "could not minify lib.js")
+	assert.Contains(t, err.Error(), "uglify: unexpected token")
+	assert.Contains(t, err.Error(), "terser: unexpected token")
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+
+	// already minified
+	res, err = compress.Js(context.Background(), path.Join(dir, "lib.min.js"), getMinifiers(t, "terser"), compress.MinifyOptions{})
+	assert.Nil(t, res)
+	assert.Nil(t, err)
+}
+
+func TestGetJsMinifier(t *testing.T) {
+	for _, name := range []string{"uglify", "terser", "esbuild"} {
+		m, err := compress.GetJsMinifier(name)
+		assert.Nil(t, err)
+		assert.Equal(t, name, m.Name())
+	}
+
+	_, err := compress.GetJsMinifier("closure")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/compress"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimizeSVG(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "comments, declaration and whitespace",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Generator: editor -->
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24">
+    <g>
+        <circle cx="12" cy="12" r="10"></circle>
+    </g>
+</svg>
+`,
+			expected: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><g><circle cx="12" cy="12" r="10"/></g></svg>`,
+		},
+		{
+			name: "editor metadata",
+			input: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd" inkscape:version="1.0">
+  <metadata><rdf:RDF><cc:Work rdf:about=""/></rdf:RDF></metadata>
+  <sodipodi:namedview pagecolor="#ffffff"><inkscape:grid/></sodipodi:namedview>
+  <rect inkscape:label="bg" width="1" height="1"/>
+</svg>`,
+			expected: `<svg xmlns="http://www.w3.org/2000/svg"><rect width="1" height="1"/></svg>`,
+		},
+		{
+			name:     "path precision",
+			input:    `<svg><path d="M10.123456 -0.00001L1.4999.5c0-1.25e-2 3.14159,2z"/><polygon points="0.33333,0.66666 1,1"/><rect x="0.123456"/></svg>`,
+			expected: `<svg><path d="M10.123 0L1.5.5c0-0.013 3.142,2z"/><polygon points="0.333,0.667 1,1"/><rect x="0.123456"/></svg>`,
+		},
+		{
+			name:     "rounded integer followed by a decimal",
+			input:    `<svg><path d="M1.9999.5"/></svg>`,
+			expected: `<svg><path d="M2 .5"/></svg>`,
+		},
+		{
+			name:     "compact arc flags",
+			input:    `<svg><path d="M0 0a1 1 0 01.5.5A1.00001 1 0 1,1 2.33333 2a1 1 0 10.25.25 1 1 0 0 1 3 3"/></svg>`,
+			expected: `<svg><path d="M0 0a1 1 0 01.5.5A1 1 0 1,1 2.333 2a1 1 0 10.25.25 1 1 0 0 1 3 3"/></svg>`,
+		},
+		{
+			name:     "significant whitespace",
+			input:    `<svg><text x="0"> a <tspan> b </tspan> </text><g xml:space="preserve"> <g> </g></g></svg>`,
+			expected: `<svg><text x="0"> a <tspan> b </tspan> </text><g xml:space="preserve"> <g> </g></g></svg>`,
+		},
+		{
+			name:     "escaping",
+			input:    `<svg><style><![CDATA[a > b { fill: red }]]></style><title>a &amp; b &lt;c&gt;</title><a href="?a=1&amp;b=&quot;2&quot;"/></svg>`,
+			expected: `<svg><style>a &gt; b { fill: red }</style><title>a &amp; b &lt;c&gt;</title><a href="?a=1&amp;b=&quot;2&quot;"/></svg>`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			assert.Nil(t, compress.OptimizeSVG(&out, strings.NewReader(tc.input)))
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestOptimizeSVGUnsupported(t *testing.T) {
+	cases := map[string]string{
+		"invalid":  `<svg><g></svg>`,
+		"unclosed": `<svg><g>`,
+		"entities": `<!DOCTYPE svg [<!ENTITY ns "http://www.w3.org/2000/svg">]><svg/>`,
+		"encoding": `<?xml version="1.0" encoding= ?><svg/>`,
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			assert.NotNil(t, compress.OptimizeSVG(&out, strings.NewReader(input)))
+			assert.Empty(t, out.String())
+		})
+	}
+}
+
+func TestSVG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "svg")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "icon.svg")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("<svg>\n  <!-- icon -->\n  <g/>\n</svg>\n"), 0644))
+
+	out, err := compress.SVG(context.Background(), file)
+	assert.Nil(t, err)
+	assert.NotNil(t, out)
+	assert.Equal(t, path.Join(dir, "icon.min.svg"), *out)
+
+	content, err := ioutil.ReadFile(*out)
+	assert.Nil(t, err)
+	assert.Equal(t, "<svg><g/></svg>", string(content))
+
+	// the minified file already exists
+	out, err = compress.SVG(context.Background(), file)
+	assert.Nil(t, err)
+	assert.Nil(t, out)
+
+	// already minified
+	out, err = compress.SVG(context.Background(), path.Join(dir, "icon.min.svg"))
+	assert.Nil(t, err)
+	assert.Nil(t, out)
+
+	// invalid files are not minified
+	invalid := path.Join(dir, "invalid.svg")
+	assert.Nil(t, ioutil.WriteFile(invalid, []byte("<svg>"), 0644))
+	out, err = compress.SVG(context.Background(), invalid)
+	assert.NotNil(t, err)
+	assert.Nil(t, out)
+	_, err = os.Stat(path.Join(dir, "invalid.min.svg"))
+	assert.True(t, os.IsNotExist(err))
+}
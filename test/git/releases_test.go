@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cdnjs/tools/git"
+	"github.com/cdnjs/tools/packages"
+
+	githubapi "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+const releasesDoc = `[
+	{
+		"tag_name": "v2.0.0-beta.1", "prerelease": true, "published_at": "2020-01-03T00:00:00Z",
+		"assets": [{ "name": "a.js", "browser_download_url": "https://example.com/2.0.0-beta.1/a.js" }]
+	},
+	{
+		"tag_name": "v1.3.0", "prerelease": true, "published_at": "2020-01-03T00:00:00Z",
+		"assets": [{ "name": "a.js", "browser_download_url": "https://example.com/1.3.0/a.js" }]
+	},
+	{
+		"tag_name": "v1.2.0", "draft": true,
+		"assets": [{ "name": "a.js", "browser_download_url": "https://example.com/1.2.0/a.js" }]
+	},
+	{
+		"tag_name": "v1.1.0", "published_at": "2020-01-02T00:00:00Z",
+		"assets": [
+			{ "name": "a.js", "browser_download_url": "https://example.com/1.1.0/a.js" },
+			{ "name": "a.zip", "browser_download_url": "https://example.com/1.1.0/a.zip" },
+			{ "name": "a.min.js", "browser_download_url": "https://example.com/1.1.0/a.min.js" }
+		]
+	},
+	{
+		"tag_name": "v1.0.0", "published_at": "2020-01-01T00:00:00Z",
+		"assets": [{ "name": "a.zip", "browser_download_url": "https://example.com/1.0.0/a.zip" }]
+	}
+]`
+
+// Starts a fake GitHub API listing the releases of any repository.
+func fakeGitHub() (*httptest.Server, *githubapi.Client) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, releasesDoc)
+	}))
+	client := githubapi.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return server, client
+}
+
+func newReleaseConfig(basePath string) *packages.Autoupdate {
+	source, target := "github-release", "https://github.com/a/b"
+	return &packages.Autoupdate{
+		Source: &source,
+		Target: &target,
+		FileMap: []packages.FileMap{
+			{BasePath: &basePath, Files: []string{"{a,b}.js"}},
+		},
+	}
+}
+
+func TestGetReleaseVersions(t *testing.T) {
+	server, client := fakeGitHub()
+	defer server.Close()
+
+	versions, err := git.GetReleaseVersionsWithClient(context.Background(), client, newReleaseConfig(""), 10)
+	assert.Nil(t, err)
+
+	// drafts, releases without matching assets and prereleases with
+	// a stable version are ignored, the other prereleases are kept
+	names := make([]string, 0)
+	for _, v := range versions {
+		names = append(names, v.Version)
+		assert.Equal(t, "github-release", v.Source)
+		assert.Len(t, v.Assets, 1)
+		assert.Equal(t, "a.js", v.Assets[0].Name)
+	}
+	assert.Equal(t, []string{"2.0.0-beta.1", "1.1.0"}, names)
+}
+
+func TestGetReleaseVersionsBasePath(t *testing.T) {
+	server, client := fakeGitHub()
+	defer server.Close()
+
+	_, err := git.GetReleaseVersionsWithClient(context.Background(), client, newReleaseConfig("dist"), 10)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "basePath `dist` is not supported")
+}
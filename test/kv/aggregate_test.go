@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cdnjs/tools/kv"
+	"github.com/cdnjs/tools/packages"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStore delays reads, so that concurrent read-modify-writes interleave.
+type slowStore struct {
+	kv.ConditionalStore
+}
+
+func (s slowStore) Read(ctx context.Context, ns kv.Namespace, key string) ([]byte, error) {
+	time.Sleep(time.Millisecond)
+	return s.ConditionalStore.Read(ctx, ns, key)
+}
+
+func TestStoreWriteIfRevision(t *testing.T) {
+	stores, cleanup := createStores(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for name, store := range stores {
+		store := store.(kv.ConditionalStore)
+		t.Run(name, func(t *testing.T) {
+			pair := &kv.Pair{Key: "a", Value: []byte("a"), Metadata: &kv.FileMetadata{Revision: 1}}
+			assert.Nil(t, store.WriteIfRevision(ctx, kv.AggregatedMetadataNamespace, pair, 0))
+
+			err := store.WriteIfRevision(ctx, kv.AggregatedMetadataNamespace, pair, 0)
+			assert.Equal(t, kv.RevisionMismatchError{Key: "a", Expected: 0, Actual: 1}, err)
+
+			pair = &kv.Pair{Key: "a", Value: []byte("b"), Metadata: &kv.FileMetadata{Revision: 2}}
+			assert.Nil(t, store.WriteIfRevision(ctx, kv.AggregatedMetadataNamespace, pair, 1))
+
+			v, err := store.Read(ctx, kv.AggregatedMetadataNamespace, "a")
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("b"), v)
+		})
+	}
+}
+
+func TestConcurrentAggregatedMetadataUpdates(t *testing.T) {
+	stores, cleanup := createStores(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// a package object for each update, they are modified when aggregated
+	newPackage := func(version string) *packages.Package {
+		return &packages.Package{
+			Name:        strPtr("pkg"),
+			Description: strPtr("a package"),
+			Keywords:    []string{"pkg"},
+			Version:     strPtr(version),
+		}
+	}
+	update := func(store kv.Store, version string) error {
+		_, _, err := kv.UpdateAggregatedMetadata(ctx, store, newPackage(version), version,
+			packages.Asset{Version: version, Files: []string{"a.js"}})
+		return err
+	}
+
+	removed := []string{"0.1.0", "0.2.0", "0.3.0", "0.4.0"}
+	added := make([]string, 0)
+	for i := 0; i < 12; i++ {
+		added = append(added, fmt.Sprintf("1.%d.0", i))
+	}
+	sort.Strings(added)
+
+	for name, store := range stores {
+		store := slowStore{store.(kv.ConditionalStore)}
+		t.Run(name, func(t *testing.T) {
+			for _, version := range removed {
+				assert.Nil(t, update(store, version))
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, len(added)+len(removed))
+			for _, version := range added {
+				wg.Add(1)
+				go func(version string) {
+					defer wg.Done()
+					errs <- update(store, version)
+				}(version)
+			}
+			for _, version := range removed {
+				wg.Add(1)
+				go func(version string) {
+					defer wg.Done()
+					_, _, err := kv.RemoveVersionFromAggregatedMetadata(ctx, store, newPackage(version), version)
+					errs <- err
+				}(version)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				assert.Nil(t, err)
+			}
+
+			// no update was lost
+			versions, err := kv.GetVersionsFromAggregatedMetadata(ctx, store, "pkg")
+			assert.Nil(t, err)
+			sort.Strings(versions)
+			assert.Equal(t, added, versions)
+
+			res, err := store.List(ctx, kv.AggregatedMetadataNamespace, "pkg", "")
+			assert.Nil(t, err)
+			assert.Equal(t, []kv.Key{
+				{Name: "pkg", Metadata: &kv.FileMetadata{Revision: int64(2*len(removed) + len(added))}},
+			}, res.Keys)
+		})
+	}
+}
+
+// unconditionalStore is a store which cannot check revisions (ex. Workers KV),
+// with delayed reads, counting the listings of aggregated metadata.
+type unconditionalStore struct {
+	kv.Store
+	aggregatedLists *int32
+}
+
+func (s unconditionalStore) List(ctx context.Context, ns kv.Namespace, prefix, cursor string) (*kv.ListResult, error) {
+	if ns == kv.AggregatedMetadataNamespace {
+		atomic.AddInt32(s.aggregatedLists, 1)
+	}
+	return s.Store.List(ctx, ns, prefix, cursor)
+}
+
+func (s unconditionalStore) Read(ctx context.Context, ns kv.Namespace, key string) ([]byte, error) {
+	value, err := s.Store.Read(ctx, ns, key)
+	time.Sleep(time.Millisecond)
+	return value, err
+}
+
+func TestUnconditionalAggregatedMetadataUpdates(t *testing.T) {
+	ctx := context.Background()
+	store := unconditionalStore{kv.NewMemoryStore(), new(int32)}
+
+	versions := make([]string, 0)
+	for i := 0; i < 12; i++ {
+		versions = append(versions, fmt.Sprintf("1.%d.0", i))
+	}
+	sort.Strings(versions)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(versions))
+	for _, version := range versions {
+		wg.Add(1)
+		go func(version string) {
+			defer wg.Done()
+			if _, err := kv.UpdateKVVersion(ctx, store, "pkg", version, []string{"a.js"}); err != nil {
+				errs <- err
+				return
+			}
+			pkg := &packages.Package{
+				Name:        strPtr("pkg"),
+				Description: strPtr("a package"),
+				Keywords:    []string{"pkg"},
+				Version:     strPtr(version),
+			}
+			_, _, err := kv.UpdateAggregatedMetadata(ctx, store, pkg, version,
+				packages.Asset{Version: version, Files: []string{"a.js"}})
+			errs <- err
+		}(version)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	// the last update includes the versions of the concurrent ones
+	aggregated, err := kv.GetVersionsFromAggregatedMetadata(ctx, store, "pkg")
+	assert.Nil(t, err)
+	sort.Strings(aggregated)
+	assert.Equal(t, versions, aggregated)
+
+	// revisions are not listed
+	assert.Equal(t, int32(0), atomic.LoadInt32(store.aggregatedLists))
+}
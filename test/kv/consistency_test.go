@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditPackageConsistent(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0", "1.1.0")
+
+	report, err := kv.AuditPackage(ctx, store, "pkg", false)
+	assert.Nil(t, err)
+	assert.Empty(t, report)
+}
+
+func TestAuditPackage(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0", "1.1.0")
+
+	// a crash before the aggregated metadata and package entry were updated
+	_, err := kv.UpdateKVVersion(ctx, store, "pkg", "1.2.0", []string{"b.js"})
+	assert.Nil(t, err)
+	// a version entry removed without the aggregated metadata
+	assert.Nil(t, store.Delete(ctx, kv.VersionsNamespace, []string{"pkg/1.0.0"}))
+
+	expected := []kv.Inconsistency{
+		{Kind: kv.StalePackageVersion, Package: "pkg", Key: "pkg", Message: "1.1.0 instead of 1.2.0"},
+		{Kind: kv.MissingAggregatedVersion, Package: "pkg", Key: "1.2.0"},
+		{Kind: kv.StaleAggregatedVersion, Package: "pkg", Key: "1.0.0"},
+		{Kind: kv.OrphanedFile, Package: "pkg", Key: "pkg/1.0.0/a.js.br"},
+		{Kind: kv.OrphanedFile, Package: "pkg", Key: "pkg/1.0.0/a.js.gz"},
+		{Kind: kv.MissingFile, Package: "pkg", Key: "pkg/1.2.0/b.js"},
+		{Kind: kv.OrphanedSRI, Package: "pkg", Key: "pkg/1.0.0/a.js"},
+	}
+
+	report, err := kv.AuditPackage(ctx, store, "pkg", false)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, report)
+
+	report, err = kv.AuditPackage(ctx, store, "pkg", true)
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		expected[i].Repaired = true
+	}
+	assert.Equal(t, expected, report)
+
+	pkg, err := kv.GetPackage(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.0", *pkg.Version)
+
+	versions, err := kv.GetVersionsFromAggregatedMetadata(ctx, store, "pkg")
+	assert.Nil(t, err)
+	sort.Strings(versions)
+	assert.Equal(t, []string{"1.1.0", "1.2.0"}, versions)
+
+	// only the files remain
+	report, err = kv.AuditPackage(ctx, store, "pkg", true)
+	assert.Nil(t, err)
+	assert.Equal(t, expected[3:], report)
+}
+
+func TestAuditMissingPackage(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0")
+
+	_, err := kv.UpdateKVVersion(ctx, store, "other", "1.0.0", []string{})
+	assert.Nil(t, err)
+
+	pkgs, err := kv.ListPackages(ctx, store)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other", "pkg"}, pkgs)
+
+	report, err := kv.AuditPackage(ctx, store, "other", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []kv.Inconsistency{
+		{Kind: kv.MissingPackage, Package: "other", Key: "other"},
+	}, report)
+}
+
+func TestAuditRawCompressedFile(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0")
+
+	// a .tar.gz asset is published raw, under its own name
+	assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/x.tar.gz", Value: []byte("tar"), Metadata: &kv.FileMetadata{Raw: true}},
+	}))
+	_, err := kv.UpdateKVVersion(ctx, store, "pkg", "1.0.0", []string{"a.js", "x.tar.gz"})
+	assert.Nil(t, err)
+
+	report, err := kv.AuditPackage(ctx, store, "pkg", false)
+	assert.Nil(t, err)
+	assert.Empty(t, report)
+}
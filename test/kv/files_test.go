@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFilesMetadata(t *testing.T) {
+	stores, cleanup := createStores(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for name, store := range stores {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+				{Key: "a/1.0.0/a.js.gz", Value: []byte("a"), Metadata: &kv.FileMetadata{ETag: "1"}},
+				{Key: "a/1.0.0/b.png", Value: []byte("b"), Metadata: &kv.FileMetadata{ETag: "2"}},
+				{Key: "a/1.0.01/a.js.gz", Value: []byte("a"), Metadata: &kv.FileMetadata{ETag: "3"}},
+			}))
+
+			metadata, err := kv.GetFilesMetadata(ctx, store, "a", "1.0.0")
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]*kv.FileMetadata{
+				"a/1.0.0/a.js.gz": {ETag: "1"},
+				"a/1.0.0/b.png":   {ETag: "2"},
+			}, metadata)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenamePackage(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0", "1.1.0")
+
+	_, err := kv.Publish(ctx, store, kv.NewPublishManifest("pkg", "1.1.0", "digest"), nil)
+	assert.Nil(t, err)
+
+	assert.Nil(t, kv.RenamePackage(ctx, store, "pkg", "new-pkg"))
+
+	// the publication manifest of the old package is not copied
+	_, err = kv.GetPublishManifest(ctx, store, "new-pkg", "1.1.0")
+	assert.IsType(t, kv.KeyNotFoundError{}, err)
+	_, err = kv.GetPublishManifest(ctx, store, "pkg", "1.1.0")
+	assert.Nil(t, err)
+
+	// the versions, files and SRIs are copied
+	versions, err := kv.GetVersions(ctx, store, "new-pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+
+	files, err := kv.GetVersion(ctx, store, "new-pkg/1.1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.js"}, files)
+
+	value, err := store.Read(ctx, kv.FilesNamespace, "new-pkg/1.1.0/a.js.br")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("br"), value)
+
+	manifest, err := kv.GetSRIManifest(ctx, store, "new-pkg", "1.1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a.js": "sha512-a"}, manifest)
+
+	res, err := store.List(ctx, kv.SRIsNamespace, "new-pkg/", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []kv.Key{
+		{Name: "new-pkg/1.0.0/a.js", Metadata: &kv.FileMetadata{SRI: "sha512-a"}},
+		{Name: "new-pkg/1.1.0/a.js", Metadata: &kv.FileMetadata{SRI: "sha512-a"}},
+	}, res.Keys)
+
+	versions, err = kv.GetVersionsFromAggregatedMetadata(ctx, store, "new-pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+
+	pkg, err := kv.GetPackage(ctx, store, "new-pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, "new-pkg", *pkg.Name)
+	assert.Equal(t, "1.1.0", *pkg.Version)
+	assert.Equal(t, []string{"pkg"}, pkg.FormerNames)
+	assert.Nil(t, pkg.RenamedTo)
+
+	// the old package is kept and redirects to the new one
+	value, err = store.Read(ctx, kv.FilesNamespace, "pkg/1.1.0/a.js.br")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("br"), value)
+
+	old, err := kv.GetPackage(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, "new-pkg", *old.RenamedTo)
+
+	// the new name exists now
+	assert.Equal(t, kv.PackageExistsError{Name: "new-pkg"}, kv.RenamePackage(ctx, store, "pkg", "new-pkg"))
+}
+
+func TestRemovePackage(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0", "1.1.0")
+
+	// another package sharing the prefix
+	assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+		{Key: "pkg2/1.0.0/a.js.br", Value: []byte("br")},
+	}))
+
+	deleted, err := kv.RemovePackage(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Len(t, deleted[kv.FilesNamespace], 4)
+	assert.Len(t, deleted[kv.SRIsNamespace], 2)
+	assert.Len(t, deleted[kv.VersionsNamespace], 2)
+	assert.Len(t, deleted[kv.VersionMetadataNamespace], 2)
+	assert.Equal(t, []string{"pkg"}, deleted[kv.PackagesNamespace])
+	assert.Equal(t, []string{"pkg"}, deleted[kv.AggregatedMetadataNamespace])
+
+	for _, ns := range kv.Namespaces {
+		res, err := store.List(ctx, ns, "pkg", "")
+		assert.Nil(t, err)
+		if ns == kv.FilesNamespace {
+			assert.Equal(t, []kv.Key{{Name: "pkg2/1.0.0/a.js.br"}}, res.Keys)
+		} else {
+			assert.Empty(t, res.Keys, ns)
+		}
+	}
+
+	// nothing left to remove
+	deleted, err = kv.RemovePackage(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Empty(t, deleted[kv.FilesNamespace])
+	assert.Empty(t, deleted[kv.PackagesNamespace])
+}
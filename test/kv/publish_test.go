@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates steps recording their application, the step named
+// by fail returns an error.
+func recordingSteps(applied *[]string, fail string) []kv.PublishStep {
+	steps := make([]kv.PublishStep, 0)
+	for _, name := range []string{"files", "version", "package"} {
+		name := name
+		steps = append(steps, kv.PublishStep{Name: name, Apply: func(ctx context.Context) error {
+			if name == fail {
+				return errors.New("boom")
+			}
+			*applied = append(*applied, name)
+			return nil
+		}})
+	}
+	return steps
+}
+
+func TestPublishResume(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+
+	newManifest := func(digest string) *kv.PublishManifest {
+		m := kv.NewPublishManifest("pkg", "1.0.0", digest)
+		m.AddKeys(kv.FilesNamespace, "pkg/1.0.0/a.js.br", "pkg/1.0.0/a.js.gz")
+		m.AddKeys(kv.VersionsNamespace, "pkg/1.0.0")
+		return m
+	}
+
+	// interrupted after the files
+	applied := make([]string, 0)
+	ok, err := kv.Publish(ctx, store, newManifest("a"), recordingSteps(&applied, "version"))
+	assert.NotNil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"files"}, applied)
+
+	m, err := kv.GetPublishManifest(ctx, store, "pkg", "1.0.0")
+	assert.Nil(t, err)
+	assert.False(t, m.Committed)
+	assert.Equal(t, []string{"files"}, m.Completed)
+	assert.Equal(t, []string{"pkg/1.0.0/a.js.br", "pkg/1.0.0/a.js.gz"}, m.Keys[kv.FilesNamespace])
+
+	// resumed after the files
+	applied = make([]string, 0)
+	ok, err = kv.Publish(ctx, store, newManifest("a"), recordingSteps(&applied, ""))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"version", "package"}, applied)
+
+	m, err = kv.GetPublishManifest(ctx, store, "pkg", "1.0.0")
+	assert.Nil(t, err)
+	assert.True(t, m.Committed)
+	assert.Equal(t, []string{"files", "version", "package"}, m.Completed)
+
+	// already published
+	applied = make([]string, 0)
+	ok, err = kv.Publish(ctx, store, newManifest("a"), recordingSteps(&applied, ""))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, applied)
+
+	// new content is published again
+	applied = make([]string, 0)
+	ok, err = kv.Publish(ctx, store, newManifest("b"), recordingSteps(&applied, ""))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"files", "version", "package"}, applied)
+}
+
+func TestPublishManifestIsNotAVersion(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0")
+
+	_, err := kv.Publish(ctx, store, kv.NewPublishManifest("pkg", "1.0.0", "a"), nil)
+	assert.Nil(t, err)
+
+	versions, err := kv.GetVersions(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0"}, versions)
+
+	report, err := kv.AuditPackage(ctx, store, "pkg", false)
+	assert.Nil(t, err)
+	assert.Empty(t, report)
+
+	_, err = kv.TakedownVersion(ctx, store, "pkg", "1.0.0")
+	assert.Nil(t, err)
+	_, err = kv.GetPublishManifest(ctx, store, "pkg", "1.0.0")
+	assert.IsType(t, kv.KeyNotFoundError{}, err)
+}
+
+func TestPublishDeletesKeysOfInterruptedPublication(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+
+	assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js.gz", Value: []byte("a")},
+		{Key: "pkg/1.0.0/old.js.gz", Value: []byte("old")},
+	}))
+	assert.Nil(t, store.WriteBulk(ctx, kv.SRIsNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/old.js", Metadata: &kv.FileMetadata{SRI: "sha512-old"}},
+	}))
+
+	// interrupted after the files
+	m := kv.NewPublishManifest("pkg", "1.0.0", "a")
+	m.AddKeys(kv.FilesNamespace, "pkg/1.0.0/a.js.gz", "pkg/1.0.0/old.js.gz")
+	m.AddKeys(kv.SRIsNamespace, "pkg/1.0.0/old.js")
+	m.AddKeys(kv.VersionsNamespace, "pkg/1.0.0")
+	applied := make([]string, 0)
+	_, err := kv.Publish(ctx, store, m, recordingSteps(&applied, "version"))
+	assert.NotNil(t, err)
+
+	// other content is published, without old.js
+	m = kv.NewPublishManifest("pkg", "1.0.0", "b")
+	m.AddKeys(kv.FilesNamespace, "pkg/1.0.0/a.js.gz")
+	m.AddKeys(kv.VersionsNamespace, "pkg/1.0.0")
+	applied = make([]string, 0)
+	_, err = kv.Publish(ctx, store, m, recordingSteps(&applied, ""))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"files", "version", "package"}, applied)
+
+	res, err := store.List(ctx, kv.FilesNamespace, "pkg/", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []kv.Key{{Name: "pkg/1.0.0/a.js.gz"}}, res.Keys)
+
+	res, err = store.List(ctx, kv.SRIsNamespace, "pkg/", "")
+	assert.Nil(t, err)
+	assert.Empty(t, res.Keys)
+
+	// the files of a committed publication are served, they are kept
+	m = kv.NewPublishManifest("pkg", "1.0.0", "c")
+	_, err = kv.Publish(ctx, store, m, nil)
+	assert.Nil(t, err)
+	_, err = store.Read(ctx, kv.FilesNamespace, "pkg/1.0.0/a.js.gz")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSRIManifest(t *testing.T) {
+	stores, cleanup := createStores(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for name, store := range stores {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			_, err := kv.GetSRIManifest(ctx, store, "a", "1.0.0")
+			assert.IsType(t, kv.KeyNotFoundError{}, err)
+
+			_, err = kv.UpdateKVVersion(ctx, store, "a", "1.0.0", []string{"a.js", "a.png"})
+			assert.Nil(t, err)
+
+			sris := map[string]string{
+				"a.js":  "sha512-js",
+				"a.png": "sha384-png sha512-png",
+			}
+			_, err = kv.UpdateKVSRIManifest(ctx, store, "a", "1.0.0", sris)
+			assert.Nil(t, err)
+
+			manifest, err := kv.GetSRIManifest(ctx, store, "a", "1.0.0")
+			assert.Nil(t, err)
+			assert.Equal(t, sris, manifest)
+
+			// the manifest is not a version
+			versions, err := kv.GetVersions(ctx, store, "a")
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"1.0.0"}, versions)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createStores(t *testing.T) (map[string]kv.Store, func()) {
+	dir, err := ioutil.TempDir("", "kv")
+	assert.Nil(t, err)
+
+	fsStore, err := kv.NewFSStore(dir)
+	assert.Nil(t, err)
+
+	stores := map[string]kv.Store{
+		"memory": kv.NewMemoryStore(),
+		"fs":     fsStore,
+	}
+	return stores, func() { os.RemoveAll(dir) }
+}
+
+func TestStoreReadWriteDelete(t *testing.T) {
+	stores, cleanup := createStores(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for name, store := range stores {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Read(ctx, kv.FilesNamespace, "a/1.0.0/a.js.gz")
+			assert.IsType(t, kv.KeyNotFoundError{}, err)
+
+			err = store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+				{Key: "a/1.0.0/a.js.gz", Value: []byte("a"), Metadata: &kv.FileMetadata{ETag: "1"}},
+				{Key: "a/1.0.0/b.js.gz", Value: []byte("b")},
+			})
+			assert.Nil(t, err)
+
+			v, err := store.Read(ctx, kv.FilesNamespace, "a/1.0.0/a.js.gz")
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("a"), v)
+
+			// namespaces are isolated
+			_, err = store.Read(ctx, kv.VersionsNamespace, "a/1.0.0/a.js.gz")
+			assert.IsType(t, kv.KeyNotFoundError{}, err)
+
+			res, err := store.List(ctx, kv.FilesNamespace, "a/", "")
+			assert.Nil(t, err)
+			assert.Equal(t, "", res.Cursor)
+			assert.Equal(t, []kv.Key{
+				{Name: "a/1.0.0/a.js.gz", Metadata: &kv.FileMetadata{ETag: "1"}},
+				{Name: "a/1.0.0/b.js.gz"},
+			}, res.Keys)
+
+			assert.Nil(t, store.Delete(ctx, kv.FilesNamespace, []string{"a/1.0.0/a.js.gz", "missing"}))
+
+			_, err = store.Read(ctx, kv.FilesNamespace, "a/1.0.0/a.js.gz")
+			assert.IsType(t, kv.KeyNotFoundError{}, err)
+		})
+	}
+}
+
+func TestStoreListCursor(t *testing.T) {
+	stores, cleanup := createStores(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for name, store := range stores {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			var pairs []*kv.Pair
+			var expected []string
+			for i := 0; i < 2500; i++ {
+				key := fmt.Sprintf("pkg/%04d", i)
+				pairs = append(pairs, &kv.Pair{Key: key, Value: []byte("[]")})
+				expected = append(expected, key)
+			}
+			pairs = append(pairs, &kv.Pair{Key: "other/1.0.0", Value: []byte("[]")})
+			assert.Nil(t, store.WriteBulk(ctx, kv.VersionsNamespace, pairs))
+
+			var names []string
+			var cursor string
+			pages := 0
+			for {
+				res, err := store.List(ctx, kv.VersionsNamespace, "pkg/", cursor)
+				assert.Nil(t, err)
+				for _, k := range res.Keys {
+					names = append(names, k.Name)
+				}
+				pages++
+				if res.Cursor == "" {
+					break
+				}
+				cursor = res.Cursor
+			}
+
+			assert.Equal(t, 3, pages)
+			assert.True(t, sort.StringsAreSorted(names))
+			assert.Equal(t, expected, names)
+
+			versions, err := kv.GetVersions(ctx, store, "pkg")
+			assert.Nil(t, err)
+			assert.Len(t, versions, 2500)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+	"github.com/cdnjs/tools/packages"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// Publishes versions of a package as kv-pump does.
+func publishVersions(t *testing.T, store kv.Store, versions ...string) {
+	ctx := context.Background()
+
+	for _, version := range versions {
+		newPackage := func() *packages.Package {
+			return &packages.Package{
+				Name:        strPtr("pkg"),
+				Description: strPtr("a package"),
+				Keywords:    []string{"pkg"},
+				Version:     strPtr(version),
+			}
+		}
+		prefix := "pkg/" + version + "/"
+
+		assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+			{Key: prefix + "a.js.br", Value: []byte("br")},
+			{Key: prefix + "a.js.gz", Value: []byte("gz")},
+		}))
+		assert.Nil(t, store.WriteBulk(ctx, kv.SRIsNamespace, []*kv.Pair{
+			{Key: prefix + "a.js", Metadata: &kv.FileMetadata{SRI: "sha512-a"}},
+		}))
+		_, err := kv.UpdateKVVersion(ctx, store, "pkg", version, []string{"a.js"})
+		assert.Nil(t, err)
+		_, err = kv.UpdateKVSRIManifest(ctx, store, "pkg", version, map[string]string{"a.js": "sha512-a"})
+		assert.Nil(t, err)
+		_, _, err = kv.UpdateAggregatedMetadata(ctx, store, newPackage(), version,
+			packages.Asset{Version: version, Files: []string{"a.js"}})
+		assert.Nil(t, err)
+		assert.Nil(t, kv.UpdateKVPackage(ctx, store, newPackage()))
+	}
+}
+
+func TestTakedownVersion(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0", "1.1.0")
+
+	res, err := kv.TakedownVersion(ctx, store, "pkg", "1.1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pkg/1.1.0/a.js.br", "pkg/1.1.0/a.js.gz"}, res.DeletedKeys)
+	assert.Equal(t, strPtr("1.0.0"), res.LatestVersion)
+
+	for ns, key := range map[kv.Namespace]string{
+		kv.FilesNamespace:    "pkg/1.1.0/a.js.gz",
+		kv.SRIsNamespace:     "pkg/1.1.0/a.js",
+		kv.VersionsNamespace: "pkg/1.1.0",
+	} {
+		_, err := store.Read(ctx, ns, key)
+		assert.IsType(t, kv.KeyNotFoundError{}, err, key)
+	}
+	_, err = kv.GetSRIManifest(ctx, store, "pkg", "1.1.0")
+	assert.IsType(t, kv.KeyNotFoundError{}, err)
+
+	// the other version is untouched
+	_, err = store.Read(ctx, kv.FilesNamespace, "pkg/1.0.0/a.js.gz")
+	assert.Nil(t, err)
+
+	versions, err := kv.GetVersions(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0"}, versions)
+
+	versions, err = kv.GetVersionsFromAggregatedMetadata(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0"}, versions)
+
+	pkg, err := kv.GetPackage(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, strPtr("1.0.0"), pkg.Version)
+
+	// the takedown is persisted, even once the version is published again
+	takenDown, err := kv.GetTakenDownVersions(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.1.0"}, takenDown)
+
+	publishVersions(t, store, "1.1.0")
+	takenDown, err = kv.GetTakenDownVersions(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.1.0"}, takenDown)
+
+	versions, err = kv.GetVersions(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+}
+
+func TestTakedownLastVersion(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0")
+
+	res, err := kv.TakedownVersion(ctx, store, "pkg", "1.0.0")
+	assert.Nil(t, err)
+	assert.Nil(t, res.LatestVersion)
+
+	_, err = kv.GetPackage(ctx, store, "pkg")
+	assert.IsType(t, kv.KeyNotFoundError{}, err)
+
+	versions, err := kv.GetVersionsFromAggregatedMetadata(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestTakedownMissingVersion(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+	publishVersions(t, store, "1.0.0")
+
+	res, err := kv.TakedownVersion(ctx, store, "pkg", "2.0.0")
+	assert.Nil(t, err)
+	assert.Empty(t, res.DeletedKeys)
+	assert.Equal(t, strPtr("1.0.0"), res.LatestVersion)
+
+	// a version taken down before it was published is not imported either
+	takenDown, err := kv.GetTakenDownVersions(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2.0.0"}, takenDown)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdnjs/tools/compress"
+	"github.com/cdnjs/tools/kv"
+	"github.com/cdnjs/tools/sri"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySRIs(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+
+	js := []byte("alert(1);")
+	css := []byte("a{color:red}")
+	png := []byte("not really a png")
+	jsSRI, err := sri.CalculateSRIWithAlgorithms(js, []string{"sha384", "sha512"})
+	assert.Nil(t, err)
+
+	_, err = kv.UpdateKVVersion(ctx, store, "pkg", "1.0.0", []string{"a.js", "b.css", "c.png", "d.js"})
+	assert.Nil(t, err)
+	_, err = kv.UpdateKVSRIManifest(ctx, store, "pkg", "1.0.0", map[string]string{"a.js": jsSRI})
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js.gz", Value: compress.Gzip9Bytes(js)},
+		{Key: "pkg/1.0.0/b.css", Value: css},
+		{Key: "pkg/1.0.0/c.png", Value: png},
+	}))
+	assert.Nil(t, store.WriteBulk(ctx, kv.SRIsNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js", Metadata: &kv.FileMetadata{SRI: jsSRI}},
+		{Key: "pkg/1.0.0/b.css", Metadata: &kv.FileMetadata{SRI: sri.CalculateSRI([]byte("a{color:blue}"))}},
+		{Key: "pkg/1.0.0/d.js", Metadata: &kv.FileMetadata{SRI: sri.CalculateSRI(js)}},
+		{Key: "pkg/0.9.0/old.js", Metadata: &kv.FileMetadata{SRI: sri.CalculateSRI(js)}},
+	}))
+
+	report, err := kv.VerifySRIs(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, []kv.SRIMismatch{
+		{Key: "pkg/1.0.0/b.css", Expected: sri.CalculateSRI([]byte("a{color:blue}")), Actual: sri.CalculateSRI(css)},
+	}, report.Mismatches)
+	assert.Equal(t, []string{"pkg/1.0.0/c.png"}, report.MissingSRIs)
+	assert.Equal(t, []string{"pkg/1.0.0/d.js"}, report.MissingFiles)
+	assert.Equal(t, []string{"pkg/0.9.0/old.js"}, report.OrphanedSRIs)
+}
+
+func TestVerifySRIsOK(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+
+	js := []byte("alert(1);")
+	_, err := kv.UpdateKVVersion(ctx, store, "pkg", "1.0.0", []string{"a.js"})
+	assert.Nil(t, err)
+	_, err = kv.UpdateKVSRIManifest(ctx, store, "pkg", "1.0.0", map[string]string{"a.js": sri.CalculateSRI(js)})
+	assert.Nil(t, err)
+	assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js.br", Value: []byte("ignored, the gzip variant is read first")},
+		{Key: "pkg/1.0.0/a.js.gz", Value: compress.Gzip9Bytes(js)},
+	}))
+	assert.Nil(t, store.WriteBulk(ctx, kv.SRIsNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js", Metadata: &kv.FileMetadata{SRI: sri.CalculateSRI(js)}},
+	}))
+
+	report, err := kv.VerifySRIs(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 1, report.Checked)
+}
+
+func TestVerifySRIsLegacyVersion(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+
+	// published before every file had an SRI, without SRI manifest
+	js := []byte("alert(1);")
+	_, err := kv.UpdateKVVersion(ctx, store, "pkg", "1.0.0", []string{"a.js", "b.css", "c.png"})
+	assert.Nil(t, err)
+	assert.Nil(t, store.WriteBulk(ctx, kv.FilesNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js.gz", Value: compress.Gzip9Bytes(js)},
+		{Key: "pkg/1.0.0/b.css", Value: []byte("a{color:red}")},
+		{Key: "pkg/1.0.0/c.png", Value: []byte("not really a png")},
+	}))
+	assert.Nil(t, store.WriteBulk(ctx, kv.SRIsNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/a.js", Metadata: &kv.FileMetadata{SRI: sri.CalculateSRI(js)}},
+	}))
+
+	report, err := kv.VerifySRIs(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Checked)
+	assert.Equal(t, []string{"pkg/1.0.0/b.css"}, report.MissingSRIs)
+	assert.Equal(t, []string{"pkg/1.0.0/c.png"}, report.Unsigned)
+	assert.False(t, report.OK())
+
+	// only the files which had no SRI then are missing one
+	assert.Nil(t, store.WriteBulk(ctx, kv.SRIsNamespace, []*kv.Pair{
+		{Key: "pkg/1.0.0/b.css", Metadata: &kv.FileMetadata{SRI: sri.CalculateSRI([]byte("a{color:red}"))}},
+	}))
+	report, err = kv.VerifySRIs(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, []string{"pkg/1.0.0/c.png"}, report.Unsigned)
+	assert.True(t, report.OK())
+}
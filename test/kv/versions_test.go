@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdnjs/tools/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLatestVersion(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewMemoryStore()
+
+	latest, err := kv.GetLatestVersion(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Nil(t, latest)
+
+	publishVersions(t, store, "1.0.0", "2.0.0-beta.1")
+	_, err = kv.UpdateKVVersion(ctx, store, "pkg", "1.1.0", []string{})
+	assert.Nil(t, err)
+
+	// the empty 1.1.0 is ignored and the prerelease is not stable
+	latest, err = kv.GetLatestVersion(ctx, store, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, strPtr("1.0.0"), latest)
+
+	// 1.2.0 being published is not listed yet
+	latest, err = kv.GetLatestVersion(ctx, store, "pkg", "1.2.0")
+	assert.Nil(t, err)
+	assert.Equal(t, strPtr("1.2.0"), latest)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cdnjs/tools/npm"
+	"github.com/cdnjs/tools/packages"
+	"github.com/cdnjs/tools/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const registryDoc = `{
+	"versions": {
+		"1.0.0": { "dist": { "tarball": "http://registry.npmjs.org/1.0.0.tgz", "integrity": "sha512-AAAA", "shasum": "abcd" } },
+		"1.1.0": { "dist": { "tarball": "http://registry.npmjs.org/1.1.0.tgz" }, "deprecated": "use 1.2.0" },
+		"1.2.0": { "dist": { "tarball": "http://registry.npmjs.org/1.2.0.tgz" } },
+		"2.0.0-beta.1": { "dist": { "tarball": "http://registry.npmjs.org/2.0.0-beta.1.tgz" } },
+		"2.0.0-beta.2": { "dist": { "tarball": "http://registry.npmjs.org/2.0.0-beta.2.tgz" } },
+		"0.0.0-canary.1": { "dist": { "tarball": "http://registry.npmjs.org/0.0.0-canary.1.tgz" } }
+	},
+	"time": {
+		"created": "2020-01-01T00:00:00.000Z",
+		"modified": "2020-01-09T00:00:00.000Z",
+		"0.9.0": "2019-12-01T00:00:00.000Z",
+		"1.0.0": "2020-01-01T00:00:00.000Z",
+		"1.1.0": "2020-01-02T00:00:00.000Z",
+		"1.2.0": "2020-01-03T00:00:00.000Z",
+		"2.0.0-beta.1": "2020-01-04T00:00:00.000Z",
+		"2.0.0-beta.2": "2020-01-05T00:00:00.000Z",
+		"0.0.0-canary.1": "2020-01-06T00:00:00.000Z"
+	},
+	"dist-tags": {
+		"latest": "1.2.0",
+		"next": "2.0.0-beta.2",
+		"canary": "0.0.0-canary.1"
+	}
+}`
+
+// Starts a fake registry, recording the requested paths.
+func fakeRegistry(paths *[]string) (*httptest.Server, *npm.Client) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*paths = append(*paths, r.URL.EscapedPath())
+		fmt.Fprint(w, registryDoc)
+	}))
+	return server, npm.NewClient(server.URL, server.URL, "", time.Second)
+}
+
+func versionNames(versions []version.Version) []string {
+	names := make([]string, 0)
+	for _, v := range versions {
+		names = append(names, v.Version)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func skippedReasons(skipped []npm.SkippedVersion) map[string]string {
+	reasons := make(map[string]string)
+	for _, v := range skipped {
+		reasons[v.Version] = v.Reason
+	}
+	return reasons
+}
+
+func TestGetVersions(t *testing.T) {
+	var paths []string
+	server, client := fakeRegistry(&paths)
+	defer server.Close()
+
+	ctx := context.Background()
+	target := "@scope/name"
+
+	t.Run("scoped name and deprecated versions", func(t *testing.T) {
+		versions, skipped, latest, err := client.GetVersions(ctx, &packages.Autoupdate{Target: &target})
+		assert.Nil(t, err)
+
+		assert.Equal(t, "/@scope%2fname", paths[len(paths)-1])
+		assert.Equal(t, "1.2.0", *latest)
+		assert.Equal(t, []string{"0.0.0-canary.1", "1.0.0", "1.2.0", "2.0.0-beta.1", "2.0.0-beta.2"}, versionNames(versions))
+		assert.Equal(t, map[string]string{
+			"1.1.0": "deprecated: use 1.2.0",
+			"0.9.0": "unpublished",
+		}, skippedReasons(skipped))
+
+		for _, v := range versions {
+			if v.Version == "1.0.0" {
+				assert.Equal(t, "sha512-AAAA", v.Integrity)
+				assert.Equal(t, "abcd", v.Shasum)
+			}
+		}
+	})
+
+	t.Run("latest dist-tag", func(t *testing.T) {
+		versions, skipped, _, err := client.GetVersions(ctx, &packages.Autoupdate{
+			Target:   &target,
+			DistTags: []string{"latest"},
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{"1.0.0", "1.2.0"}, versionNames(versions))
+		assert.Equal(t, "not reachable from dist-tags", skippedReasons(skipped)["2.0.0-beta.1"])
+		assert.Equal(t, "not reachable from dist-tags", skippedReasons(skipped)["0.0.0-canary.1"])
+	})
+
+	t.Run("latest and next dist-tags", func(t *testing.T) {
+		versions, _, _, err := client.GetVersions(ctx, &packages.Autoupdate{
+			Target:   &target,
+			DistTags: []string{"latest", "next"},
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{"1.0.0", "1.2.0", "2.0.0-beta.1", "2.0.0-beta.2"}, versionNames(versions))
+	})
+}
+
+func TestGetVersionsErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{"not found", http.StatusNotFound, `{"error":"Not found"}`, npm.NotFoundError{Name: "pkg"}},
+		{"rate limited", http.StatusTooManyRequests, ``, npm.RateLimitedError{Name: "pkg"}},
+		{"invalid json", http.StatusOK, `{`, npm.MalformedDocumentError{}},
+		{"no tarball", http.StatusOK, `{"versions":{"1.0.0":{"dist":{}}},"time":{"1.0.0":"2020-01-01T00:00:00.000Z"}}`, npm.MalformedDocumentError{}},
+		{"no time stamp", http.StatusOK, `{"versions":{"1.0.0":{"dist":{"tarball":"a.tgz"}}},"time":{}}`, npm.MalformedDocumentError{}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			client := npm.NewClient(server.URL, server.URL, "", time.Second)
+			client.RetryDelay = time.Millisecond
+			target := "pkg"
+
+			_, _, _, err := client.GetVersions(context.Background(), &packages.Autoupdate{Target: &target})
+			assert.IsType(t, tc.expected, err)
+			if _, ok := tc.expected.(npm.MalformedDocumentError); !ok {
+				assert.Equal(t, tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestClientAuthAndRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	client := npm.NewClient(server.URL+"/", server.URL, "secret", time.Second)
+	client.RetryDelay = time.Millisecond
+
+	exists, err := client.Exists(context.Background(), "pkg")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	client.Token = ""
+	exists, err = client.Exists(context.Background(), "pkg")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "registry returned 401 for pkg")
+	assert.False(t, exists)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/downloads/point/last-month/pkg":
+			fmt.Fprint(w, `{"downloads": 1200}`)
+		case "/downloads/point/last-month/malformed":
+			fmt.Fprint(w, `{"downloads": "many"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := npm.NewClient(server.URL+"/", server.URL, "", time.Second)
+	client.RetryDelay = time.Millisecond
+	ctx := context.Background()
+
+	exists, err := client.Exists(ctx, "missing")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// a registry error is returned instead of panicking
+	_, err = client.Exists(ctx, "broken")
+	assert.NotNil(t, err)
+
+	md, err := client.GetMonthlyDownload(ctx, "pkg")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1200), md.Downloads)
+
+	_, err = client.GetMonthlyDownload(ctx, "malformed")
+	assert.IsType(t, npm.MalformedDocumentError{}, err)
+
+	_, err = client.GetMonthlyDownload(ctx, "broken")
+	assert.NotNil(t, err)
+
+	// the server is unreachable
+	server.Close()
+	_, err = client.Exists(ctx, "pkg")
+	assert.NotNil(t, err)
+	_, err = client.GetMonthlyDownload(ctx, "pkg")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cdnjs/tools/packages"
+	"github.com/cdnjs/tools/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a number of one-byte files, returning their move operations.
+func createFiles(t *testing.T, dir string, n int) []packages.NpmFileMoveOp {
+	files := make([]packages.NpmFileMoveOp, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("icon-%d.svg", i)
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, name), []byte("a"), 0644))
+		files = append(files, packages.NpmFileMoveOp{From: name, To: name})
+	}
+	return files
+}
+
+func TestCheckLimitsLargePackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "limits")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// large packages, such as icon sets, are within the default limits
+	files := createFiles(t, dir, 2500)
+
+	pkg := &packages.Package{}
+	report, err := pkg.CheckLimits(dir, files)
+	assert.Nil(t, err)
+	assert.False(t, report.Exceeded())
+	assert.Equal(t, 2500, report.Files)
+	assert.Equal(t, util.MaxVersionFiles, report.MaxFiles)
+	assert.Equal(t, int64(2500), report.Size)
+
+	// the limits can be lowered per package
+	maxFiles := 2000
+	pkg.Limits = &packages.Limits{MaxFiles: &maxFiles}
+	report, err = pkg.CheckLimits(dir, files)
+	assert.Nil(t, err)
+	assert.True(t, report.Exceeded())
+	assert.Equal(t, 2000, report.MaxFiles)
+	assert.Equal(t, util.MaxVersionSize, report.MaxSize)
+}
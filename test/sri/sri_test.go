@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cdnjs/tools/sri"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateSRIWithAlgorithms(t *testing.T) {
+	content := []byte("alert('hello');\n")
+
+	cases := []struct {
+		algorithms []string
+		expected   string
+	}{
+		{
+			[]string{"sha256"},
+			"sha256-I6cQ15C81BfES1nF5/j2iEvVDYdVExzggnQA5pDi6Yc=",
+		},
+		{
+			[]string{"sha384", "sha512"},
+			"sha384-MDum2fgAl1a2tIPZ0GlPfC2KhiOcxSixAAPQlOzp0Pd2gnveVnXMd8pA6XUi8TOg " +
+				"sha512-bbNV2lo3ZXEc/CxaElRW6MVFWL7FHrZ/9tF6+limdukHB2zO1LIyOMJCJVRaJtHftC0y6rZ96/7x4oATa0T/7w==",
+		},
+	}
+
+	for _, tc := range cases {
+		res, err := sri.CalculateSRIWithAlgorithms(content, tc.algorithms)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, res)
+	}
+
+	_, err := sri.CalculateSRIWithAlgorithms(content, []string{"md5"})
+	assert.Equal(t, sri.UnsupportedAlgorithmError{Algorithm: "md5"}, err)
+}
+
+func TestParseAlgorithms(t *testing.T) {
+	algorithms, err := sri.ParseAlgorithms("SHA384, sha512,")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sha384", "sha512"}, algorithms)
+
+	_, err = sri.ParseAlgorithms("sha1")
+	assert.Equal(t, sri.UnsupportedAlgorithmError{Algorithm: "sha1"}, err)
+
+	_, err = sri.ParseAlgorithms("")
+	assert.NotNil(t, err)
+}
+
+func TestCalculateSRIDefault(t *testing.T) {
+	assert.Equal(t, sri.DefaultAlgorithms, sri.Algorithms)
+	assert.Regexp(t, `^sha512-[A-Za-z0-9+/]{86}==$`, sri.CalculateSRI([]byte("a")))
+}
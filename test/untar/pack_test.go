@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/cdnjs/tools/untar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Writes files in a new directory, with the given modification time.
+func createDir(t *testing.T, files map[string]string, mtime time.Time) string {
+	dir, err := ioutil.TempDir("", "pack")
+	assert.Nil(t, err)
+
+	for name, content := range files {
+		file := path.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(path.Dir(file), 0700))
+		assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0600))
+		assert.Nil(t, os.Chtimes(file, mtime, mtime))
+	}
+	return dir
+}
+
+func TestPack(t *testing.T) {
+	files := map[string]string{
+		"b.js":          "b",
+		"a.js.br":       "a",
+		"dist/c.css.gz": "c",
+	}
+
+	dir1 := createDir(t, files, time.Now())
+	defer os.RemoveAll(dir1)
+	dir2 := createDir(t, files, time.Now().Add(-time.Hour))
+	defer os.RemoveAll(dir2)
+
+	var out1, out2 bytes.Buffer
+	assert.Nil(t, untar.Pack(dir1, &out1))
+	assert.Nil(t, untar.Pack(dir2, &out2))
+	assert.Equal(t, out1.Bytes(), out2.Bytes())
+
+	names := make([]string, 0)
+	inflated := make(map[string]string)
+	err := untar.Inflate(&out1, func(name string, r io.Reader) error {
+		content, err := ioutil.ReadAll(r)
+		assert.Nil(t, err)
+		names = append(names, name)
+		inflated[name] = string(content)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.js.br", "b.js", "dist/c.css.gz"}, names)
+	assert.Equal(t, files, inflated)
+}
+
+func TestPackUnsupportedFile(t *testing.T) {
+	dir := createDir(t, map[string]string{"a.js": "a"}, time.Now())
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.Symlink("a.js", path.Join(dir, "b.js")))
+
+	var out bytes.Buffer
+	assert.NotNil(t, untar.Pack(dir, &out))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/untar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+// Creates a gzipped tarball with the given entries.
+func createTar(t *testing.T, entries []entry) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			Size:     int64(len(e.content)),
+		}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		assert.Nil(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte(e.content))
+			assert.Nil(t, err)
+		}
+	}
+
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, zw.Close())
+	return &buf
+}
+
+// Lists the files of a directory relative to it.
+func listDir(t *testing.T, dir string) map[string]string {
+	files := make(map[string]string)
+	var walk func(string)
+	walk = func(rel string) {
+		infos, err := ioutil.ReadDir(path.Join(dir, rel))
+		assert.Nil(t, err)
+		for _, info := range infos {
+			name := path.Join(rel, info.Name())
+			if info.IsDir() {
+				walk(name)
+				continue
+			}
+			content, err := ioutil.ReadFile(path.Join(dir, name))
+			assert.Nil(t, err)
+			files[name] = string(content)
+		}
+	}
+	walk("")
+	return files
+}
+
+var maliciousEntries = []entry{
+	{name: "package/", typeflag: tar.TypeDir},
+	{name: "package/a.js", typeflag: tar.TypeReg, content: "a"},
+	{name: "package/../../evil.js", typeflag: tar.TypeReg, content: "evil"},
+	{name: "../evil.js", typeflag: tar.TypeReg, content: "evil"},
+	{name: "package/dist/../../../evil.js", typeflag: tar.TypeReg, content: "evil"},
+	{name: "/package/abs.js", typeflag: tar.TypeReg, content: "abs"},
+	{name: "package/link.js", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+	{name: "package/hard.js", typeflag: tar.TypeLink, linkname: "package/a.js"},
+	{name: "package/dev", typeflag: tar.TypeChar},
+	{name: "package/fifo", typeflag: tar.TypeFifo},
+	{name: "package/dist/b.js", typeflag: tar.TypeReg, content: "b"},
+}
+
+func TestToDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "untar")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	dest := path.Join(root, "a", "b", "dest")
+	assert.Nil(t, os.MkdirAll(dest, 0755))
+
+	skipped := make(map[string]string)
+	opts := untar.Options{
+		Rename: func(name string) string {
+			return strings.TrimPrefix(name, "package/")
+		},
+		OnSkip: func(name, reason string) {
+			skipped[name] = reason
+		},
+	}
+	assert.Nil(t, untar.ToDir(createTar(t, maliciousEntries), dest, opts))
+
+	assert.Equal(t, map[string]string{
+		"a.js":           "a",
+		"dist/b.js":      "b",
+		"package/abs.js": "abs",
+	}, listDir(t, dest))
+
+	// nothing was written outside of the destination
+	for name := range listDir(t, root) {
+		assert.True(t, strings.HasPrefix(name, "a/b/dest/"), name)
+	}
+
+	assert.Equal(t, map[string]string{
+		"package/../../evil.js":         "located outside of the archive",
+		"../evil.js":                    "located outside of the archive",
+		"package/dist/../../../evil.js": "located outside of the archive",
+		"package/link.js":               "symlink to `/etc/passwd`",
+		"package/hard.js":               "hard link to `package/a.js`",
+		"package/dev":                   "unsupported type 33",
+		"package/fifo":                  "unsupported type 36",
+	}, skipped)
+}
+
+func TestToDirExistingSymlink(t *testing.T) {
+	dest, err := ioutil.TempDir("", "untar")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dest)
+
+	target, err := ioutil.TempFile("", "untar-target")
+	assert.Nil(t, err)
+	target.Close()
+	defer os.Remove(target.Name())
+
+	assert.Nil(t, os.Symlink(target.Name(), path.Join(dest, "a.js")))
+
+	tarball := createTar(t, []entry{
+		{name: "a.js", typeflag: tar.TypeReg, content: "overwritten"},
+	})
+	assert.NotNil(t, untar.ToDir(tarball, dest, untar.Options{}))
+
+	content, err := ioutil.ReadFile(target.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, "", string(content))
+}
+
+func TestInflateTooManyEntries(t *testing.T) {
+	entries := []entry{
+		{name: "a.js", typeflag: tar.TypeReg, content: "a"},
+		{name: "b.js", typeflag: tar.TypeReg, content: "b"},
+		{name: "dir/", typeflag: tar.TypeDir},
+		{name: "c.js", typeflag: tar.TypeReg, content: "c"},
+	}
+
+	files := make([]string, 0)
+	onFile := func(name string, r io.Reader) error {
+		files = append(files, name)
+		return nil
+	}
+
+	err := untar.InflateWithOptions(createTar(t, entries), untar.Options{MaxEntries: 3}, onFile)
+	assert.Equal(t, untar.TooManyEntriesError{Limit: 3}, err)
+
+	files = files[:0]
+	err = untar.InflateWithOptions(createTar(t, entries), untar.Options{MaxEntries: 4}, onFile)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.js", "b.js", "c.js"}, files)
+}
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"a.js", "a.js", true},
+		{"./a/b.js", "a/b.js", true},
+		{"/a.js", "a.js", true},
+		{"//a/../b.js", "b.js", true},
+		{"a/../../b.js", "", false},
+		{"..", "", false},
+		{"/", "", false},
+		{"..a.js", "..a.js", true},
+	}
+
+	for _, tc := range cases {
+		name, ok := untar.Sanitize(tc.name)
+		assert.Equal(t, tc.ok, ok, tc.name)
+		assert.Equal(t, tc.expected, name, tc.name)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cdnjs/tools/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Files of a typical npm package, as found in the workspace.
+var packageFiles = []string{
+	".npmignore",
+	"LICENSE",
+	"README.md",
+	"package.json",
+	"dist/.hidden.js",
+	"dist/lib.js",
+	"dist/lib.js.map",
+	"dist/lib.min.js",
+	"dist/lib.min.js.map",
+	"dist/css/lib.css",
+	"dist/css/lib.min.css",
+	"dist/css/themes/dark.css",
+	"dist/img/logo.png",
+	"dist/img/logo.svg",
+	"dist/locale/en.js",
+	"dist/locale/fr.js",
+	"dist/locale/zh-cn.js",
+	"dist/.cache/lib.js",
+	"lang/1.js",
+	"lang/2.js",
+	"lang/3.js",
+	"lang/10.js",
+	"src/index.ts",
+}
+
+func createPackage(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "glob")
+	assert.Nil(t, err)
+	for _, f := range packageFiles {
+		assert.Nil(t, os.MkdirAll(path.Dir(path.Join(dir, f)), 0755))
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, f), []byte(f), 0644))
+	}
+	return dir
+}
+
+// Patterns found in cdnjs packages and the files node-glob matches.
+var globCorpus = []struct {
+	pattern  string
+	expected []string
+}{
+	{"*.js", []string{}},
+	{"*", []string{"LICENSE", "README.md", "package.json"}},
+	{"dist/*.js", []string{"dist/lib.js", "dist/lib.min.js"}},
+	{"dist/*.min.js", []string{"dist/lib.min.js"}},
+	{"./dist/*.min.js", []string{"dist/lib.min.js"}},
+	{"dist/**/*.css", []string{"dist/css/lib.css", "dist/css/lib.min.css", "dist/css/themes/dark.css"}},
+	{"**/*.min.js", []string{"dist/lib.min.js"}},
+	{"**/*.map", []string{"dist/lib.js.map", "dist/lib.min.js.map"}},
+	{"dist/**", []string{
+		"dist/css/lib.css", "dist/css/lib.min.css", "dist/css/themes/dark.css",
+		"dist/img/logo.png", "dist/img/logo.svg",
+		"dist/lib.js", "dist/lib.js.map", "dist/lib.min.js", "dist/lib.min.js.map",
+		"dist/locale/en.js", "dist/locale/fr.js", "dist/locale/zh-cn.js",
+	}},
+	{"dist/**/**/*.svg", []string{"dist/img/logo.svg"}},
+	{"dist/*.{js,css}", []string{"dist/lib.js", "dist/lib.min.js"}},
+	{"dist/**/*.{png,svg}", []string{"dist/img/logo.png", "dist/img/logo.svg"}},
+	{"dist/{css/*.css,img/*.png}", []string{"dist/css/lib.css", "dist/css/lib.min.css", "dist/img/logo.png"}},
+	{"lang/{1..3}.js", []string{"lang/1.js", "lang/2.js", "lang/3.js"}},
+	{"lang/?.js", []string{"lang/1.js", "lang/2.js", "lang/3.js"}},
+	{"lang/[12]*.js", []string{"lang/1.js", "lang/10.js", "lang/2.js"}},
+	{"lang/[!12].js", []string{"lang/3.js"}},
+	{"dist/locale/[a-f]*.js", []string{"dist/locale/en.js", "dist/locale/fr.js"}},
+	{"dist/*.@(js|map)", []string{"dist/lib.js", "dist/lib.js.map", "dist/lib.min.js", "dist/lib.min.js.map"}},
+	{"dist/lib?(.min).js", []string{"dist/lib.js", "dist/lib.min.js"}},
+	{"dist/.*.js", []string{"dist/.hidden.js"}},
+	{"dist/.cache/*.js", []string{"dist/.cache/lib.js"}},
+	{"**/.npmignore", []string{".npmignore"}},
+	{"!**/*.{js,map,css}", []string{
+		"LICENSE", "README.md", "dist/img/logo.png", "dist/img/logo.svg", "package.json", "src/index.ts",
+	}},
+	{"{a}.js", []string{}},
+}
+
+func TestListFilesGlob(t *testing.T) {
+	dir := createPackage(t)
+	defer os.RemoveAll(dir)
+
+	for _, tc := range globCorpus {
+		list, err := util.ListFilesGlob(context.Background(), dir, tc.pattern)
+		assert.Nil(t, err, tc.pattern)
+		assert.Equal(t, tc.expected, list, tc.pattern)
+	}
+}
+
+func TestListFilesGlobInvalid(t *testing.T) {
+	dir := createPackage(t)
+	defer os.RemoveAll(dir)
+
+	_, err := util.ListFilesGlob(context.Background(), dir, "dist/*.@(js")
+	assert.NotNil(t, err)
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"**/*.js", "a/b/c.js", true},
+		{"**/*.js", "c.js", true},
+		{"**/*.js", "a/.b/c.js", false},
+		{"a/**/b.js", "a/b.js", true},
+		{"*.js", ".eslintrc.js", false},
+		{"\\*.js", "*.js", true},
+		{"\\*.js", "a.js", false},
+		{"!!*.js", "a.js", true},
+		{"[]a].js", "].js", true},
+		{"+(a|b).js", "abba.js", true},
+		{"*(a|b).js", "ab.js", true},
+		{"*(a|b).js", "c.js", false},
+	}
+
+	for _, tc := range cases {
+		g, err := util.CompileGlob(tc.pattern)
+		assert.Nil(t, err, tc.pattern)
+		assert.Equal(t, tc.match, g.Match(tc.path), tc.pattern+" "+tc.path)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cdnjs/tools/version"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// Reads a tarball into a map of file names to contents.
+func readTar(t *testing.T, r io.Reader) map[string]string {
+	zr, err := gzip.NewReader(r)
+	assert.Nil(t, err)
+
+	files := make(map[string]string)
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+
+		content, err := ioutil.ReadAll(tr)
+		assert.Nil(t, err)
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func TestDownloadReleaseAssets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "content of %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	v := version.Version{
+		Version: "1.0.0",
+		Source:  "github-release",
+		Assets: []version.Asset{
+			{Name: "lib.js", URL: server.URL + "/lib.js"},
+			{Name: "lib.min.js", URL: server.URL + "/lib.min.js"},
+		},
+	}
+
+	tarball, err := version.DownloadTar(context.Background(), v)
+	assert.Nil(t, err)
+	defer tarball.Close()
+	assert.Equal(t, map[string]string{
+		"lib.js":     "content of /lib.js",
+		"lib.min.js": "content of /lib.min.js",
+	}, readTar(t, tarball))
+}
+
+func TestDownloadReleaseAssetsLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.js":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/chunked.js":
+			// flushing before writing prevents the Content-Length header
+			w.(http.Flusher).Flush()
+		}
+		w.Write(make([]byte, 600))
+	}))
+	defer server.Close()
+
+	newVersion := func(names ...string) version.Version {
+		v := version.Version{Version: "1.0.0", Source: "github-release"}
+		for _, name := range names {
+			v.Assets = append(v.Assets, version.Asset{Name: name, URL: server.URL + "/" + name})
+		}
+		return v
+	}
+	read := func(v version.Version, limit int64) (map[string]string, error) {
+		tarball, err := version.DownloadTarWithLimit(context.Background(), v, limit)
+		if err != nil {
+			return nil, err
+		}
+		defer tarball.Close()
+		content, err := ioutil.ReadAll(tarball)
+		if err != nil {
+			return nil, err
+		}
+		return readTar(t, strings.NewReader(string(content))), nil
+	}
+
+	// assets with and without announced size are streamed
+	files, err := read(newVersion("sized.js", "chunked.js"), 1200)
+	assert.Nil(t, err)
+	assert.Len(t, files["sized.js"], 600)
+	assert.Len(t, files["chunked.js"], 600)
+
+	// the limit applies to the sum of the assets
+	_, err = read(newVersion("sized.js", "sized2.js"), 1000)
+	assert.Equal(t, version.SizeError{URL: server.URL + "/sized2.js", Limit: 400}, errors.Cause(err))
+	_, err = read(newVersion("sized.js", "chunked.js"), 1000)
+	assert.Equal(t, version.SizeError{URL: server.URL + "/chunked.js", Limit: 400}, errors.Cause(err))
+
+	_, err = read(newVersion("sized.js", "missing.js"), 1200)
+	assert.Equal(t, version.StatusError{URL: server.URL + "/missing.js", StatusCode: 404}, errors.Cause(err))
+}
+
+func TestDownloadStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	v := version.Version{
+		Version: "1.0.0",
+		Source:  "npm",
+		Tarball: server.URL + "/pkg-1.0.0.tgz",
+	}
+
+	_, err := version.DownloadTar(context.Background(), v)
+	assert.Equal(t, version.StatusError{URL: v.Tarball, StatusCode: http.StatusNotFound}, err)
+
+	v.Tarball = ""
+	_, err = version.DownloadTar(context.Background(), v)
+	assert.NotNil(t, err)
+}
+
+func TestDownloadIntegrity(t *testing.T) {
+	content := []byte("tarball content")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	sha512Sum := sha512.Sum512(content)
+	sha1Sum := sha1.Sum(content)
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sha512Sum[:])
+	shasum := hex.EncodeToString(sha1Sum[:])
+
+	download := func(v version.Version) ([]byte, error) {
+		v.Source = "npm"
+		v.Tarball = server.URL + "/pkg-1.0.0.tgz"
+		tarball, err := version.DownloadTar(context.Background(), v)
+		if err != nil {
+			return nil, err
+		}
+		defer tarball.Close()
+		return ioutil.ReadAll(tarball)
+	}
+
+	t.Run("sha512 integrity", func(t *testing.T) {
+		bytes, err := download(version.Version{Integrity: integrity, Shasum: "00"})
+		assert.Nil(t, err)
+		assert.Equal(t, content, bytes)
+	})
+
+	t.Run("sha1 shasum", func(t *testing.T) {
+		bytes, err := download(version.Version{Shasum: shasum})
+		assert.Nil(t, err)
+		assert.Equal(t, content, bytes)
+	})
+
+	t.Run("no integrity", func(t *testing.T) {
+		bytes, err := download(version.Version{})
+		assert.Nil(t, err)
+		assert.Equal(t, content, bytes)
+	})
+
+	t.Run("integrity mismatch", func(t *testing.T) {
+		other := sha512.Sum512([]byte("tampered"))
+		_, err := download(version.Version{Integrity: "sha512-" + base64.StdEncoding.EncodeToString(other[:])})
+		assert.IsType(t, version.IntegrityError{}, err)
+	})
+
+	t.Run("shasum mismatch", func(t *testing.T) {
+		_, err := download(version.Version{Shasum: strings.Repeat("0", 40)})
+		assert.IsType(t, version.IntegrityError{}, err)
+	})
+
+	t.Run("unsupported integrity", func(t *testing.T) {
+		_, err := download(version.Version{Integrity: "md5-AAAA"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDownloadSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked.tgz" {
+			// flushing before writing prevents the Content-Length header
+			w.(http.Flusher).Flush()
+		}
+		w.Write(make([]byte, 1024))
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"/sized.tgz", "/chunked.tgz"} {
+		v := version.Version{Version: "1.0.0", Source: "npm", Tarball: server.URL + name}
+
+		tarball, err := version.DownloadTarWithLimit(context.Background(), v, 1024)
+		assert.Nil(t, err)
+		_, err = ioutil.ReadAll(tarball)
+		assert.Nil(t, err)
+		tarball.Close()
+
+		tarball, err = version.DownloadTarWithLimit(context.Background(), v, 1000)
+		if err == nil {
+			_, err = ioutil.ReadAll(tarball)
+			tarball.Close()
+		}
+		assert.Equal(t, version.SizeError{URL: v.Tarball, Limit: 1000}, err, name)
+	}
+}
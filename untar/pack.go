@@ -0,0 +1,81 @@
+package untar
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Pack writes a gzipped tarball of the content of a directory. The tarball
+// is reproducible: its entries are sorted and their timestamps, ownership
+// and permissions are normalized, so that packing the same files twice
+// produces the same bytes.
+func Pack(dir string, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	// Walk visits the files in lexical order
+	err := filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+
+		header := &tar.Header{
+			Name:    filepath.ToSlash(name),
+			ModTime: time.Unix(0, 0),
+		}
+		switch {
+		case fi.IsDir():
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			header.Mode = 0755
+		case fi.Mode().IsRegular():
+			header.Typeflag = tar.TypeReg
+			header.Mode = 0644
+			header.Size = fi.Size()
+		default:
+			return errors.Errorf("unsupported file type of %s: %s", name, fi.Mode())
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return errors.Wrapf(err, "could not write header of %s", name)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return errors.Wrap(err, "could not open file")
+		}
+		defer f.Close()
+		if _, err := io.Copy(tw, f); err != nil {
+			return errors.Wrapf(err, "could not write %s", name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "could not close tar")
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(err, "could not close gzip")
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package untar
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxEntries is the default maximum number of entries of a tarball.
+const DefaultMaxEntries = 100000
+
+// Options configures the extraction of a tarball.
+type Options struct {
+	// Rename maps the name of each entry before it is sanitized
+	// (ex. to remove a top-level directory).
+	Rename func(string) string
+
+	// MaxEntries is the maximum number of entries, of any type, in the tarball.
+	// Defaults to DefaultMaxEntries.
+	MaxEntries int
+
+	// OnSkip is called with the original name of each entry that is not
+	// extracted and the reason why. Defaults to logging.
+	OnSkip func(name, reason string)
+}
+
+// TooManyEntriesError represents a tarball with more entries than allowed.
+type TooManyEntriesError struct {
+	Limit int
+}
+
+// Error is used to satisfy the error interface.
+func (e TooManyEntriesError) Error() string {
+	return fmt.Sprintf("tarball has more than %d entries", e.Limit)
+}
+
+// Inflate calls onFile for each regular file of a gzipped tarball,
+// using the default options.
+func Inflate(gzipStream io.Reader, onFile func(string, io.Reader) error) error {
+	return InflateWithOptions(gzipStream, Options{}, onFile)
+}
+
+// InflateWithOptions calls onFile for each regular file of a gzipped tarball.
+// The name passed to onFile is a clean relative path (leading slashes are
+// removed) that cannot be outside of the archive root. Entries that would
+// escape the root, symlinks, hard links and special files are skipped.
+func InflateWithOptions(gzipStream io.Reader, opts Options, onFile func(string, io.Reader) error) error {
+	uncompressedStream, err := gzip.NewReader(gzipStream)
+	if err != nil {
+		return errors.Wrap(err, "could not create gzip reader")
+	}
+
+	maxEntries := opts.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	onSkip := opts.OnSkip
+	if onSkip == nil {
+		onSkip = func(name, reason string) {
+			log.Printf("tarball: ignoring %s: %s\n", name, reason)
+		}
+	}
+
+	tarReader := tar.NewReader(uncompressedStream)
+
+	for entries := 1; ; entries++ {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read tarball")
+		}
+		if entries > maxEntries {
+			return TooManyEntriesError{maxEntries}
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			// directories are created along with their files
+			continue
+		case tar.TypeReg, tar.TypeRegA:
+			// handled below
+		case tar.TypeSymlink:
+			onSkip(header.Name, fmt.Sprintf("symlink to `%s`", header.Linkname))
+			continue
+		case tar.TypeLink:
+			onSkip(header.Name, fmt.Sprintf("hard link to `%s`", header.Linkname))
+			continue
+		default:
+			onSkip(header.Name, fmt.Sprintf("unsupported type %x", header.Typeflag))
+			continue
+		}
+
+		name := header.Name
+		if opts.Rename != nil {
+			name = opts.Rename(name)
+		}
+		name, ok := Sanitize(name)
+		if !ok {
+			onSkip(header.Name, "located outside of the archive")
+			continue
+		}
+
+		if err := onFile(name, tarReader); err != nil {
+			return errors.Wrapf(err, "failed to handle %s", name)
+		}
+	}
+	return nil
+}
+
+// ToDir extracts the regular files of a gzipped tarball into a directory.
+func ToDir(gzipStream io.Reader, dest string, opts Options) error {
+	return InflateWithOptions(gzipStream, opts, func(name string, r io.Reader) error {
+		target := path.Join(dest, name)
+		if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+			return errors.Wrap(err, "could not create directory")
+		}
+
+		// never follow a symlink or overwrite a directory at the target
+		if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+			return errors.Errorf("%s already exists and is not a regular file", name)
+		}
+
+		out, err := os.Create(target)
+		if err != nil {
+			return errors.Wrap(err, "could not create file")
+		}
+		defer out.Close()
+
+		if _, err := io.Copy(out, r); err != nil {
+			return errors.Wrap(err, "could not write file")
+		}
+		return nil
+	})
+}
+
+// Sanitize cleans the name of an entry into a relative path, returning false
+// if the entry is the archive root or is outside of it.
+func Sanitize(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
@@ -10,6 +10,20 @@ const (
 	// MaxFileSize is the file size in bytes accepted by cdnjs (25MiB).
 	MaxFileSize int64 = 26214400
 
+	// MaxArchiveSize is the maximum size in bytes of a downloaded
+	// version archive (250MiB).
+	MaxArchiveSize int64 = 262144000
+
+	// MaxVersionFiles is the default maximum number of files
+	// published for a version. It is above the largest published
+	// packages (ex. MathJax 2 image fonts, icon sets), which
+	// must keep updating without a `limits` override.
+	MaxVersionFiles = 50000
+
+	// MaxVersionSize is the default maximum total size in bytes of the files
+	// published for a version, before optimization and compression (200MiB).
+	MaxVersionSize int64 = 209715200
+
 	// MinNpmMonthlyDownloads is the minimum number of monthly downloads
 	// from npm needed for a library to be accepted into cdnjs.
 	MinNpmMonthlyDownloads = 800
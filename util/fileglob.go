@@ -1,19 +1,18 @@
 package util
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"log"
 	"os"
-	"os/exec"
+	"sort"
 	"strings"
 
 	"github.com/karrick/godirwalk"
 )
 
-// ListFilesGlob is the legacy, slower version that uses
-// the node glob tool found here: https://github.com/cdnjs/glob
+// ListFilesGlob lists the files within the `base` directory matching a glob
+// pattern, following the semantics of node-glob (see Glob). Paths are relative
+// to the base directory and sorted.
 func ListFilesGlob(ctx context.Context, base string, pattern string) ([]string, error) {
 	list := make([]string, 0)
 
@@ -28,23 +27,33 @@ func ListFilesGlob(ctx context.Context, base string, pattern string) ([]string,
 		return list, nil
 	}
 
-	cmd := exec.Command("/glob/index.js", pattern)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	cmd.Dir = base
-	err := cmd.Run()
+	g, err := CompileGlob(pattern)
 	if err != nil {
-		fmt.Printf("%s: %s\n", err, out.String())
 		return list, err
 	}
 
-	for _, line := range strings.Split(out.String(), "\n") {
-		if strings.Trim(line, " ") != "" {
-			list = append(list, line)
-		}
-
+	err = godirwalk.Walk(base, &godirwalk.Options{
+		Callback: func(fp string, de *godirwalk.Dirent) error {
+			fp = strings.TrimLeft(strings.TrimPrefix(fp, base), "/")
+			if fp == "" {
+				return nil
+			}
+			isDir, err := de.IsDirOrSymlinkToDir()
+			if err != nil {
+				return err
+			}
+			if !isDir && g.Match(fp) {
+				list = append(list, fp)
+			}
+			return nil
+		},
+		FollowSymbolicLinks: true,
+	})
+	if err != nil {
+		return list, err
 	}
+
+	sort.Strings(list)
 	return list, nil
 }
 
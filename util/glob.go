@@ -0,0 +1,343 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Glob is a compiled glob pattern following the node-glob semantics cdnjs
+// relies on (https://github.com/isaacs/node-glob):
+//   - `*` and `?` match any characters within a path segment, `**` alone in a
+//     segment matches any number of segments,
+//   - `[abc]`, `[!abc]` and `[^abc]` match a character class,
+//   - `{a,b}` and `{1..3}` are expanded before matching,
+//   - `@(a|b)`, `?(a|b)`, `+(a|b)` and `*(a|b)` are extended globs,
+//   - a leading `!` negates the pattern,
+//   - files and directories starting with `.` are only matched by segments
+//     explicitly starting with `.`.
+type Glob struct {
+	negate   bool
+	patterns [][]globSegment
+}
+
+type globSegment struct {
+	globstar    bool
+	explicitDot bool
+	re          *regexp.Regexp
+}
+
+// CompileGlob compiles a glob pattern.
+func CompileGlob(pattern string) (*Glob, error) {
+	g := &Glob{}
+
+	// each leading `!` toggles the negation, unless it starts an extended glob
+	for strings.HasPrefix(pattern, "!") && !strings.HasPrefix(pattern, "!(") {
+		g.negate = !g.negate
+		pattern = pattern[1:]
+	}
+
+	for _, expanded := range expandBraces(pattern) {
+		segments, err := compileGlobSegments(expanded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid glob `%s`", pattern)
+		}
+		g.patterns = append(g.patterns, segments)
+	}
+	return g, nil
+}
+
+// Match determines if a slash separated relative path matches the glob.
+func (g *Glob) Match(p string) bool {
+	parts := strings.Split(strings.TrimPrefix(p, "./"), "/")
+	for _, segments := range g.patterns {
+		if matchGlobSegments(segments, parts) {
+			return !g.negate
+		}
+	}
+	if g.negate {
+		// like `**`, negated patterns do not match hidden files
+		for _, part := range parts {
+			if strings.HasPrefix(part, ".") {
+				return false
+			}
+		}
+	}
+	return g.negate
+}
+
+func matchGlobSegments(segments []globSegment, parts []string) bool {
+	if len(segments) == 0 {
+		return len(parts) == 0
+	}
+	s := segments[0]
+	if s.globstar {
+		// `**` matches zero or more segments, none of them hidden
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobSegments(segments[1:], parts[i:]) {
+				return true
+			}
+			if i < len(parts) && strings.HasPrefix(parts[i], ".") {
+				return false
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 || !s.match(parts[0]) {
+		return false
+	}
+	return matchGlobSegments(segments[1:], parts[1:])
+}
+
+func (s globSegment) match(part string) bool {
+	if part == "." || part == ".." {
+		return false
+	}
+	if strings.HasPrefix(part, ".") && !s.explicitDot {
+		return false
+	}
+	return s.re.MatchString(part)
+}
+
+// Compiles each slash separated segment of a glob.
+func compileGlobSegments(pattern string) ([]globSegment, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	segments := make([]globSegment, 0)
+	for _, part := range strings.Split(pattern, "/") {
+		if part == "" {
+			continue
+		}
+		if part == "**" {
+			// consecutive `**` are equivalent to a single one
+			if len(segments) > 0 && segments[len(segments)-1].globstar {
+				continue
+			}
+			segments = append(segments, globSegment{globstar: true})
+			continue
+		}
+		expr, err := globToRegexp(part)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, globSegment{
+			explicitDot: strings.HasPrefix(part, "."),
+			re:          re,
+		})
+	}
+	return segments, nil
+}
+
+// Translates a segment of a glob into a regular expression.
+func globToRegexp(glob string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		// extended globs
+		if strings.ContainsRune("@?+*!", rune(c)) && i+1 < len(glob) && glob[i+1] == '(' {
+			end := closingParen(glob, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated extended glob at %d", i)
+			}
+			if c == '!' {
+				return "", fmt.Errorf("negated extended glob `%s` is not supported", glob[i:end+1])
+			}
+			alternatives := make([]string, 0)
+			for _, alt := range splitTopLevel(glob[i+2:end], '|') {
+				expr, err := globToRegexp(alt)
+				if err != nil {
+					return "", err
+				}
+				alternatives = append(alternatives, expr)
+			}
+			b.WriteString("(?:" + strings.Join(alternatives, "|") + ")")
+			switch c {
+			case '?':
+				b.WriteString("?")
+			case '+':
+				b.WriteString("+")
+			case '*':
+				b.WriteString("*")
+			}
+			i = end
+			continue
+		}
+
+		switch c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		case '*':
+			b.WriteString(`[^/]*`)
+		case '?':
+			b.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			// a `]` right after the opening bracket is part of the class
+			if end == 0 || (end == 1 && (glob[i+1] == '!' || glob[i+1] == '^')) {
+				if next := strings.IndexByte(glob[i+end+2:], ']'); next >= 0 {
+					end += next + 1
+				} else {
+					end = -1
+				}
+			}
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			negated := false
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				negated = true
+				class = class[1:]
+			}
+			b.WriteString("[")
+			if negated {
+				b.WriteString("^/")
+			}
+			for _, r := range class {
+				if r == '-' {
+					b.WriteRune(r)
+				} else {
+					b.WriteString(regexp.QuoteMeta(string(r)))
+				}
+			}
+			b.WriteString("]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String(), nil
+}
+
+// Finds the index of the parenthesis closing the one at `open`.
+func closingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Splits a string on a separator, ignoring separators that are
+// escaped or nested in braces or parentheses.
+func splitTopLevel(s string, sep byte) []string {
+	parts := make([]string, 0)
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{', '(':
+			depth++
+		case '}', ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Expands the braces of a glob (ex. `a.{js,css}` -> `a.js`, `a.css`).
+// Braces without a comma or a valid range are kept as is.
+func expandBraces(pattern string) []string {
+	depth, start := 0, -1
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			body := pattern[start+1 : i]
+			alternatives := splitTopLevel(body, ',')
+			if len(alternatives) < 2 {
+				alternatives = expandRange(body)
+				if alternatives == nil {
+					continue
+				}
+			}
+			res := make([]string, 0)
+			for _, alt := range alternatives {
+				res = append(res, expandBraces(pattern[:start]+alt+pattern[i+1:])...)
+			}
+			return res
+		}
+	}
+	return []string{pattern}
+}
+
+// Expands a numeric or alphabetic range (ex. `1..3` or `a..c`),
+// returning nil if the body is not a range.
+func expandRange(body string) []string {
+	bounds := strings.Split(body, "..")
+	if len(bounds) != 2 {
+		return nil
+	}
+
+	res := make([]string, 0)
+	if from, err := strconv.Atoi(bounds[0]); err == nil {
+		to, err := strconv.Atoi(bounds[1])
+		if err != nil {
+			return nil
+		}
+		step := 1
+		if to < from {
+			step = -1
+		}
+		for n := from; n != to+step; n += step {
+			res = append(res, strconv.Itoa(n))
+		}
+		return res
+	}
+
+	if len(bounds[0]) == 1 && len(bounds[1]) == 1 {
+		from, to := bounds[0][0], bounds[1][0]
+		step := 1
+		if to < from {
+			step = -1
+		}
+		for c := int(from); c != int(to)+step; c += step {
+			res = append(res, string(rune(c)))
+		}
+		return res
+	}
+	return nil
+}
@@ -1,27 +1,181 @@
 package version
 
 import (
-	"bytes"
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/cdnjs/tools/util"
+
+	"github.com/pkg/errors"
 )
 
-func DownloadTar(ctx context.Context, v Version) bytes.Buffer {
+// DownloadTar downloads the tarball of a version, limited to
+// util.MaxArchiveSize bytes.
+func DownloadTar(ctx context.Context, v Version) (io.ReadCloser, error) {
+	return DownloadTarWithLimit(ctx, v, util.MaxArchiveSize)
+}
+
+// DownloadTarWithLimit streams the tarball of a version. It returns a
+// StatusError if the server did not respond with 200 OK. Reading fails with
+// a SizeError once more than `limit` bytes are read, and with an
+// IntegrityError at the end of the tarball if it does not match the
+// integrity or shasum provided by the registry.
+func DownloadTarWithLimit(ctx context.Context, v Version, limit int64) (io.ReadCloser, error) {
+	if v.Source == "github-release" {
+		return downloadAssets(ctx, v, limit)
+	}
 	if v.Tarball == "" {
-		panic("no tarball url provided for " + v.Version)
+		return nil, errors.Errorf("no tarball url provided for %s", v.Version)
+	}
+
+	h, expected, err := expectedDigest(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot verify %s", v.Version)
 	}
+
 	log.Printf("download %s\n", v.Tarball)
+	body, err := download(ctx, v.Tarball, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if h == nil {
+		log.Printf("%s: no integrity provided, not verifying\n", v.Tarball)
+		return body, nil
+	}
+	return &readCloser{
+		Reader: &verifyingReader{r: body, h: h, expected: expected, url: v.Tarball},
+		Closer: body,
+	}, nil
+}
 
-	resp, err := http.Get(v.Tarball)
-	util.Check(err)
-	defer resp.Body.Close()
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Starts downloading a URL, returning its size limited body.
+func download(ctx context.Context, url string, limit int64) (io.ReadCloser, error) {
+	body, _, err := downloadWithSize(ctx, url, limit)
+	return body, err
+}
 
-	var buff bytes.Buffer
-	_, err = buff.ReadFrom(resp.Body)
-	util.Check(err)
+// Starts downloading a URL, returning its size limited body and
+// its announced size, -1 if unknown.
+func downloadWithSize(ctx context.Context, url string, limit int64) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not create request")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "could not download %s", url)
+	}
 
-	return buff
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, StatusError{url, resp.StatusCode}
+	}
+
+	// reject early when the server announces the size
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, 0, SizeError{url, limit}
+	}
+
+	return &readCloser{
+		Reader: &limitedReader{r: resp.Body, limit: limit, url: url},
+		Closer: resp.Body,
+	}, resp.ContentLength, nil
+}
+
+// Downloads the assets of a GitHub release and packs them
+// at the root of a tarball, so that they can be processed
+// like any other version. The limit applies to the sum of the assets.
+// The assets are streamed, reading fails if one of them cannot be downloaded.
+func downloadAssets(ctx context.Context, v Version, limit int64) (io.ReadCloser, error) {
+	if len(v.Assets) == 0 {
+		return nil, errors.Errorf("no release assets provided for %s", v.Version)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(packAssets(ctx, v, limit, w))
+	}()
+	return r, nil
+}
+
+// Writes the gzipped tarball of the assets of a GitHub release to w.
+func packAssets(ctx context.Context, v Version, limit int64, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	for _, asset := range v.Assets {
+		size, err := packAsset(ctx, tw, asset, v, limit)
+		if err != nil {
+			return err
+		}
+		limit -= size
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "could not close tar")
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(err, "could not close gzip")
+	}
+	return nil
+}
+
+// Streams an asset into a tarball, returning its size. An asset whose size
+// is not announced is first written to a temporary file, as the size is
+// required before its content.
+func packAsset(ctx context.Context, tw *tar.Writer, asset Asset, v Version, limit int64) (int64, error) {
+	log.Printf("download %s\n", asset.URL)
+
+	body, size, err := downloadWithSize(ctx, asset.URL, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	var content io.Reader = body
+	if size < 0 {
+		f, err := ioutil.TempFile("", "asset")
+		if err != nil {
+			return 0, errors.Wrap(err, "could not create temporary file")
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		if size, err = io.Copy(f, body); err != nil {
+			return 0, errors.Wrapf(err, "could not read %s", asset.URL)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, errors.Wrap(err, "could not rewind temporary file")
+		}
+		content = f
+	}
+
+	header := &tar.Header{
+		Name:     asset.Name,
+		Mode:     0644,
+		Size:     size,
+		Typeflag: tar.TypeReg,
+		ModTime:  v.Date,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return 0, errors.Wrap(err, "could not write tar header")
+	}
+	if _, err := io.Copy(tw, content); err != nil {
+		return 0, errors.Wrapf(err, "could not read %s", asset.URL)
+	}
+	return size, nil
 }
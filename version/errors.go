@@ -0,0 +1,40 @@
+package version
+
+import (
+	"fmt"
+)
+
+// StatusError represents a download that did not return 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+// Error is used to satisfy the error interface.
+func (e StatusError) Error() string {
+	return fmt.Sprintf("download %s returned %d", e.URL, e.StatusCode)
+}
+
+// SizeError represents a download that exceeds the maximum size.
+type SizeError struct {
+	URL   string
+	Limit int64
+}
+
+// Error is used to satisfy the error interface.
+func (e SizeError) Error() string {
+	return fmt.Sprintf("download %s exceeds %d bytes", e.URL, e.Limit)
+}
+
+// IntegrityError represents a download that does not match
+// the integrity provided by the registry.
+type IntegrityError struct {
+	URL      string
+	Expected string
+	Actual   string
+}
+
+// Error is used to satisfy the error interface.
+func (e IntegrityError) Error() string {
+	return fmt.Sprintf("download %s has digest %s, expected %s", e.URL, e.Actual, e.Expected)
+}
@@ -0,0 +1,102 @@
+package version
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Hash constructors of the SRI algorithms, from the strongest to the weakest.
+var integrityAlgorithms = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha384", sha512.New384},
+	{"sha256", sha256.New},
+	{"sha1", sha1.New},
+}
+
+// Gets the hash and expected digest used to verify the tarball of
+// a version, preferring the strongest algorithm of the SRI and falling
+// back to the sha1 shasum. Returns a nil hash if there is nothing to verify.
+func expectedDigest(v Version) (hash.Hash, []byte, error) {
+	if v.Integrity != "" {
+		hashes := make(map[string]string)
+		for _, field := range strings.Fields(v.Integrity) {
+			// ignore options (ex. sha512-xxx?opt)
+			field = strings.SplitN(field, "?", 2)[0]
+			parts := strings.SplitN(field, "-", 2)
+			if len(parts) == 2 {
+				hashes[parts[0]] = parts[1]
+			}
+		}
+		for _, algo := range integrityAlgorithms {
+			if encoded, ok := hashes[algo.name]; ok {
+				digest, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "invalid integrity `%s`", v.Integrity)
+				}
+				return algo.new(), digest, nil
+			}
+		}
+		if v.Shasum == "" {
+			return nil, nil, errors.Errorf("unsupported integrity `%s`", v.Integrity)
+		}
+	}
+
+	if v.Shasum != "" {
+		digest, err := hex.DecodeString(v.Shasum)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid shasum `%s`", v.Shasum)
+		}
+		return sha1.New(), digest, nil
+	}
+
+	return nil, nil, nil
+}
+
+// verifyingReader hashes what is read and compares the digest
+// once the underlying reader is exhausted.
+type verifyingReader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected []byte
+	url      string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF {
+		if actual := v.h.Sum(nil); !bytes.Equal(actual, v.expected) {
+			return n, IntegrityError{v.url, hex.EncodeToString(v.expected), hex.EncodeToString(actual)}
+		}
+	}
+	return n, err
+}
+
+// limitedReader fails with a SizeError once more than limit bytes are read.
+type limitedReader struct {
+	r     io.Reader
+	read  int64
+	limit int64
+	url   string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, SizeError{l.url, l.limit}
+	}
+	return n, err
+}
@@ -8,12 +8,21 @@ import (
 	"github.com/gobwas/glob"
 )
 
-// Version represents a version of a git repo or npm.
+// Version represents a version of a git repo, npm or GitHub release.
 type Version struct {
-	Version string
-	Tarball string
-	Date    time.Time
-	Source  string // npm or git
+	Version   string
+	Tarball   string
+	Date      time.Time
+	Source    string  // npm, git or github-release
+	Assets    []Asset // github-release only
+	Integrity string  // npm only, SRI of the tarball (ex. sha512-...)
+	Shasum    string  // npm only, hex encoded sha1 of the tarball
+}
+
+// Asset represents a file attached to a GitHub release.
+type Asset struct {
+	Name string
+	URL  string
 }
 
 func IsVersionIgnored(config *packages.Autoupdate, version string) bool {